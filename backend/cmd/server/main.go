@@ -23,7 +23,7 @@ func main() {
 	loadEngine := engine.NewLoadEngine()
 
 	scriptService := service.NewScriptService(httpGen, scriptRepo)
-	testService := service.NewTestService(scriptRepo, historyRepo, loadEngine)
+	testService := service.NewTestService(scriptRepo, historyRepo, loadEngine, "./scripts/outputs")
 
 	mux := router.NewRouter(
 		scriptService,
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"iter"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type rateStage struct {
+	duration time.Duration
+	target   float64
+}
+
+// runArrivalRate starts iterations so that the cumulative number of started
+// iterations follows the integral of a piecewise linear rate (iterations per
+// second). Iterations run on a pool of VUs that grows from preAllocated up to
+// maxVUs; when every VU is busy an iteration waits in a backlog of up to
+// maxVUs iterations, and is dropped only when the backlog is full or the
+// scenario ends first. Each iteration gets the time it was due, so a late
+// start counts towards its response time instead of being hidden.
+func runArrivalRate(
+	ctx context.Context,
+	metrics *collector,
+	startRate float64,
+	stages []rateStage,
+	preAllocated int,
+	maxVUs int,
+	iterate iterationFunc,
+) {
+	backlog := make(chan time.Time, max(maxVUs, preAllocated))
+	var idle atomic.Int64
+	vus := 0
+	wg := sync.WaitGroup{}
+
+	startVU := func() {
+		vus++
+		idle.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vu := metrics.startVU()
+			defer metrics.stopVU(vu)
+
+			for scheduled := range backlog {
+				idle.Add(-1)
+				iterate(ctx, vu, scheduled)
+				idle.Add(1)
+			}
+		}()
+	}
+
+	for vus < preAllocated {
+		startVU()
+	}
+
+	dispatch := func(scheduled time.Time) {
+		if idle.Load() <= int64(len(backlog)) && vus < maxVUs {
+			startVU()
+		}
+		select {
+		case backlog <- scheduled:
+		default:
+			metrics.addDropped()
+		}
+	}
+
+	start := time.Now()
+	for at := range arrivalOffsets(startRate, stages) {
+		due := start.Add(at)
+		if !sleepUntil(ctx, due) {
+			break
+		}
+		dispatch(due)
+	}
+
+	end := time.Duration(0)
+	for _, stage := range stages {
+		end += stage.duration
+	}
+	sleepUntil(ctx, start.Add(end))
+
+	// Iterations still waiting when the scenario ends never start.
+	for len(backlog) > 0 {
+		select {
+		case <-backlog:
+			metrics.addDropped()
+		default:
+		}
+	}
+	close(backlog)
+	wg.Wait()
+}
+
+// arrivalOffsets yields when each iteration is due, from the start of the
+// scenario. Iteration n is due once the rate's integral reaches n; the
+// fraction of an iteration a stage ends on carries over into the next one.
+func arrivalOffsets(startRate float64, stages []rateStage) iter.Seq[time.Duration] {
+	return func(yield func(time.Duration) bool) {
+		offset := time.Duration(0)
+		from := startRate
+		scheduled := 0.0
+		next := 0.0
+
+		for _, stage := range stages {
+			seconds := stage.duration.Seconds()
+			stageTotal := (from + stage.target) / 2 * seconds
+
+			for next-scheduled < stageTotal {
+				at := solveArrival(from, stage.target, seconds, next-scheduled)
+				if !yield(offset + time.Duration(at*float64(time.Second))) {
+					return
+				}
+				next++
+			}
+
+			scheduled += stageTotal
+			offset += stage.duration
+			from = stage.target
+		}
+	}
+}
+
+// solveArrival returns the time in seconds into a stage at which n iterations
+// have been started, for a rate moving linearly from `from` to `to`.
+func solveArrival(from, to, seconds, n float64) float64 {
+	a := (to - from) / (2 * seconds)
+	if a == 0 {
+		return n / from
+	}
+
+	disc := from*from + 4*a*n
+	if disc < 0 {
+		disc = 0
+	}
+	return (-from + math.Sqrt(disc)) / (2 * a)
+}
+
+// sleepUntil waits for the deadline and reports false if ctx ended first.
+func sleepUntil(ctx context.Context, deadline time.Time) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+func TestSolveArrival(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		from, to, seconds float64
+		n                 float64
+		want              float64
+	}{
+		{"constant", 10, 10, 10, 5, 0.5},
+		{"ramp up from zero", 0, 10, 10, 2, 2},
+		{"ramp up to the end", 0, 10, 10, 50, 10},
+		{"ramp up", 2, 6, 4, 6, 2},
+		{"ramp down", 10, 0, 10, 18, 2},
+		{"ramp down to the end", 10, 0, 10, 50, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := solveArrival(tc.from, tc.to, tc.seconds, tc.n)
+			if math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("solveArrival(%v, %v, %v, %v) = %v, want %v", tc.from, tc.to, tc.seconds, tc.n, got, tc.want)
+			}
+
+			// The rate's integral up to the returned time is n.
+			a := (tc.to - tc.from) / (2 * tc.seconds)
+			if started := tc.from*got + a*got*got; math.Abs(started-tc.n) > 1e-9 {
+				t.Errorf("%v iterations started by %vs, want %v", started, got, tc.n)
+			}
+		})
+	}
+}
+
+func seconds(s ...float64) []time.Duration {
+	durations := make([]time.Duration, len(s))
+	for i, v := range s {
+		durations[i] = time.Duration(v * float64(time.Second))
+	}
+	return durations
+}
+
+func TestArrivalOffsets(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		startRate float64
+		stages    []rateStage
+		want      []time.Duration
+	}{
+		{
+			name:      "constant",
+			startRate: 4,
+			stages:    []rateStage{{time.Second, 4}},
+			want:      seconds(0, 0.25, 0.5, 0.75),
+		},
+		{
+			// 0 -> 2/s over 2s starts 2 iterations, 2/s for 1s two more,
+			// and 2 -> 0/s over 2s the last 2.
+			name:   "ramping between stages",
+			stages: []rateStage{{2 * time.Second, 2}, {time.Second, 2}, {2 * time.Second, 0}},
+			want:   seconds(0, math.Sqrt2, 2, 2.5, 3, 3+2-math.Sqrt2),
+		},
+		{
+			// The first stage starts 1.5 iterations; the half it ends on is
+			// the first half of the next stage's first iteration.
+			name:   "fractional iterations carried over",
+			stages: []rateStage{{time.Second, 3}, {time.Second, 3}},
+			want:   seconds(0, math.Sqrt(6)/3, 1+0.5/3, 1.5, 1+2.5/3),
+		},
+		{
+			name:      "idle stage",
+			startRate: 0,
+			stages:    []rateStage{{time.Second, 0}, {time.Second, 2}},
+			want:      seconds(1),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := slices.Collect(arrivalOffsets(tc.startRate, tc.stages))
+			if len(got) != len(tc.want) {
+				t.Fatalf("offsets = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if diff := got[i] - tc.want[i]; diff < -time.Microsecond || diff > time.Microsecond {
+					t.Errorf("offset %d = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestArrivalStagesUseTimeUnit(t *testing.T) {
+	var config model.ExecutorConfig
+	config.Executor = model.RampingArrivalRate
+	config.TimeUnit = "1m"
+	config.StartRate = 60
+	config.Stages = []model.Stage{{Duration: 10, Target: 120}, {Duration: 5, Target: 0}}
+
+	startRate, stages := arrivalStages(config)
+	if startRate != 1 {
+		t.Errorf("start rate = %v/s, want 1/s", startRate)
+	}
+	want := []rateStage{{10 * time.Second, 2}, {5 * time.Second, 0}}
+	if !slices.Equal(stages, want) {
+		t.Errorf("stages = %+v, want %+v", stages, want)
+	}
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+var defaultChecks = []model.Check{
+	{Type: model.CheckStatus},
+}
+
+var patterns sync.Map
+
+type checkOutcome struct {
+	name   string
+	passed bool
+}
+
+// StepChecks returns the checks evaluated for a step. Steps without checks
+// get the same "status is 2xx" check the k6 script generator emits.
+func StepChecks(step model.Step) []model.Check {
+	if len(step.Checks) == 0 {
+		return defaultChecks
+	}
+	return step.Checks
+}
+
+// CheckName returns the check's name, deriving a readable one when unset.
+func CheckName(check model.Check) string {
+	if check.Name != "" {
+		return check.Name
+	}
+
+	switch check.Type {
+	case model.CheckStatus:
+		if len(check.StatusCodes) == 0 {
+			return "status is 2xx"
+		}
+		codes := make([]string, len(check.StatusCodes))
+		for i, code := range check.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		return "status is " + strings.Join(codes, " or ")
+	case model.CheckHeader:
+		if check.Value == "" {
+			return "header " + check.Header + " is present"
+		}
+		return "header " + check.Header + " is " + check.Value
+	case model.CheckBodyContains:
+		return "body contains " + check.Value
+	case model.CheckBodyMatches:
+		return "body matches " + check.Value
+	case model.CheckJSONPath:
+		return check.Path + " is " + check.Value
+	case model.CheckMaxDuration:
+		return fmt.Sprintf("duration < %dms", check.MaxDurationMs)
+	}
+	return string(check.Type)
+}
+
+func needsBody(checks []model.Check) bool {
+	return slices.ContainsFunc(checks, func(check model.Check) bool {
+		switch check.Type {
+		case model.CheckBodyContains, model.CheckBodyMatches, model.CheckJSONPath:
+			return true
+		}
+		return false
+	})
+}
+
+// evaluateCheck reports whether the response satisfies check. A failed request
+// (resp == nil) fails every check.
+func evaluateCheck(check model.Check, resp *http.Response, body []byte, duration time.Duration) bool {
+	if resp == nil {
+		return false
+	}
+
+	switch check.Type {
+	case model.CheckStatus:
+		if len(check.StatusCodes) == 0 {
+			return resp.StatusCode >= 200 && resp.StatusCode < 300
+		}
+		return slices.Contains(check.StatusCodes, resp.StatusCode)
+	case model.CheckHeader:
+		values, ok := resp.Header[http.CanonicalHeaderKey(check.Header)]
+		if !ok {
+			return false
+		}
+		return check.Value == "" || slices.Contains(values, check.Value)
+	case model.CheckBodyContains:
+		return bytes.Contains(body, []byte(check.Value))
+	case model.CheckBodyMatches:
+		re, err := compilePattern(check.Value)
+		return err == nil && re.Match(body)
+	case model.CheckJSONPath:
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return false
+		}
+		value, ok := lookupJSON(doc, check.Path)
+		return ok && jsonString(value) == check.Value
+	case model.CheckMaxDuration:
+		return duration < time.Duration(check.MaxDurationMs)*time.Millisecond
+	}
+	return false
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// JSONPathSegments splits a path such as "data.items[0].id" or
+// "data.items.0.id" into its keys and indexes.
+func JSONPathSegments(path string) []string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func lookupJSON(doc any, path string) (any, bool) {
+	current := doc
+
+	for _, segment := range JSONPathSegments(path) {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// jsonString formats a decoded JSON value the way JavaScript's String() does
+// for scalars, so checks compare equal in the engine and in k6.
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,501 @@
+package engine
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+type counts struct {
+	total      int
+	success    int
+	failure    int
+	iterations int
+	dropped    int
+	sent       int
+	received   int
+}
+
+func (c *counts) add(other counts) {
+	c.total += other.total
+	c.success += other.success
+	c.failure += other.failure
+	c.iterations += other.iterations
+	c.dropped += other.dropped
+	c.sent += other.sent
+	c.received += other.received
+}
+
+type checkCounts struct {
+	passes int
+	fails  int
+}
+
+// shard holds the metrics recorded by one VU, so VUs never contend on a
+// shared lock. Its mutex is only contended when the collector is sampled.
+type shard struct {
+	mu sync.Mutex
+
+	counts
+	durations      histogram
+	window         histogram
+	responseTimes  histogram
+	responseWindow histogram
+	timings        [timingCount]histogram
+	checks         map[string]*checkCounts
+	checkOrder     []string
+	steps          map[stepKey]*stepStats
+	failures       map[string]*failureStats
+	custom         map[customKey]*customMetric
+	subs           map[string]*shard
+
+	// samples holds raw samples for the run's outputs until the sampler
+	// drains them. They are only kept when outputTags is set, which also
+	// tags the iteration samples.
+	samples    []Sample
+	spans      []Span
+	outputTags map[string]string
+
+	// scenarioSubs are the sub-metrics the scenario's own tags match, which
+	// also count its iterations.
+	scenarioSubs []string
+}
+
+func newShard() *shard {
+	return &shard{
+		checks:   make(map[string]*checkCounts),
+		steps:    make(map[stepKey]*stepStats),
+		failures: make(map[string]*failureStats),
+		custom:   make(map[customKey]*customMetric),
+	}
+}
+
+// requestSample is what one request adds to the metrics. duration is the
+// service time; responseTime runs from when the request should have started
+// to when it ended, the time a user would have waited.
+type requestSample struct {
+	duration     time.Duration
+	responseTime time.Duration
+	phases       timings
+	status       int
+	failure      *requestFailure
+}
+
+func (s *shard) addRequest(step stepMeta, sample requestSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recordRequest(step, sample)
+	if s.outputTags != nil {
+		s.requestSamples(step, sample, time.Now())
+	}
+
+	status := strconv.Itoa(sample.status)
+	for _, sub := range step.subs {
+		if sub.matches(step.tags, "status", status) {
+			s.sub(sub.key).recordRequest(step, sample)
+		}
+	}
+}
+
+func (s *shard) recordRequest(step stepMeta, sample requestSample) {
+	s.total++
+	s.durations.record(sample.duration)
+	s.window.record(sample.duration)
+	s.responseTimes.record(sample.responseTime)
+	s.responseWindow.record(sample.responseTime)
+	for i, phase := range sample.phases {
+		s.timings[i].record(phase)
+	}
+	s.step(step).record(sample.duration, sample.status, sample.failure == nil)
+
+	if sample.failure == nil {
+		s.success++
+		return
+	}
+
+	s.failure++
+	s.countFailure(sample.failure.category, &failureStats{
+		count:     1,
+		sample:    sample.failure.message,
+		firstSeen: time.Now(),
+	})
+}
+
+func (s *shard) countFailure(category string, stats *failureStats) {
+	current, ok := s.failures[category]
+	if !ok {
+		current = &failureStats{}
+		s.failures[category] = current
+	}
+	current.merge(stats)
+}
+
+func (s *shard) addChecks(step stepMeta, outcomes []checkOutcome) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, outcome := range outcomes {
+		passes, fails := 0, 1
+		if outcome.passed {
+			passes, fails = 1, 0
+		}
+		s.countCheck(outcome.name, passes, fails)
+		if s.outputTags != nil {
+			s.checkSample(step, outcome, now)
+		}
+
+		for _, sub := range step.subs {
+			if sub.matches(step.tags, "check", outcome.name) {
+				s.sub(sub.key).countCheck(outcome.name, passes, fails)
+			}
+		}
+	}
+}
+
+func (s *shard) countCheck(name string, passes, fails int) {
+	counts, ok := s.checks[name]
+	if !ok {
+		counts = &checkCounts{}
+		s.checks[name] = counts
+		s.checkOrder = append(s.checkOrder, name)
+	}
+	counts.passes += passes
+	counts.fails += fails
+}
+
+func (s *shard) step(meta stepMeta) *stepStats {
+	stats, ok := s.steps[meta.stepKey]
+	if !ok {
+		stats = &stepStats{stepMeta: meta}
+		s.steps[meta.stepKey] = stats
+	}
+	return stats
+}
+
+func (s *shard) addMetric(step stepMeta, metric stepMetric, value float64) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.customMetric(metric.key, metric.Type, metric.Tags).add(value, now)
+	if s.outputTags != nil {
+		s.samples = append(s.samples, Sample{Metric: metric.Name, Type: metric.Type, Time: now, Value: value, Tags: metric.tags})
+	}
+
+	for _, sub := range step.subs {
+		if sub.matches(metric.tags, "", "") {
+			s.sub(sub.key).customMetric(metric.key, metric.Type, metric.Tags).add(value, now)
+		}
+	}
+}
+
+// sub returns the shard holding the samples that match a sub-metric filter.
+func (s *shard) sub(key string) *shard {
+	if s.subs == nil {
+		s.subs = make(map[string]*shard)
+	}
+	sub, ok := s.subs[key]
+	if !ok {
+		sub = newShard()
+		s.subs[key] = sub
+	}
+	return sub
+}
+
+func (s *shard) customMetric(key customKey, kind model.MetricType, tags map[string]string) *customMetric {
+	metric, ok := s.custom[key]
+	if !ok {
+		metric = &customMetric{name: key.name, kind: kind, tags: tags}
+		s.custom[key] = metric
+	}
+	return metric
+}
+
+func (s *shard) addIteration() {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.iterations++
+	for _, key := range s.scenarioSubs {
+		s.sub(key).iterations++
+	}
+	if s.outputTags != nil {
+		s.samples = append(s.samples, Sample{Metric: "iterations", Type: model.MetricCounter, Time: now, Value: 1, Tags: s.outputTags})
+	}
+	s.mu.Unlock()
+}
+
+func (s *shard) merge(other *shard) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	s.counts.add(other.counts)
+	s.durations.merge(&other.durations)
+	s.responseTimes.merge(&other.responseTimes)
+	for i := range other.timings {
+		s.timings[i].merge(&other.timings[i])
+	}
+
+	for _, name := range other.checkOrder {
+		counts := other.checks[name]
+		s.countCheck(name, counts.passes, counts.fails)
+	}
+
+	for _, stats := range other.steps {
+		s.step(stats.stepMeta).merge(&stats.requestStats)
+	}
+
+	for category, stats := range other.failures {
+		s.countFailure(category, stats)
+	}
+
+	for key, metric := range other.custom {
+		s.customMetric(key, metric.kind, metric.tags).merge(metric)
+	}
+
+	for key, sub := range other.subs {
+		s.sub(key).merge(sub)
+	}
+}
+
+// collector gathers the metrics of one scenario. Each running VU records into
+// a shard of its own; shards of finished VUs are reused by later ones.
+type collector struct {
+	mu     sync.Mutex
+	shards []*shard
+	idle   []*shard
+
+	dropped      atomic.Int64
+	dataSent     atomic.Int64
+	dataReceived atomic.Int64
+	activeVUs    atomic.Int64
+
+	outputTags   map[string]string
+	scenarioSubs []string
+}
+
+// newCollector returns a collector for one scenario. When outputTags is set,
+// its VUs keep raw samples for the run's outputs. Iterations, dropped
+// iterations and data also count towards scenarioSubs, the keys of the
+// sub-metrics that match the scenario's tags.
+func newCollector(outputTags map[string]string, scenarioSubs []string) *collector {
+	return &collector{outputTags: outputTags, scenarioSubs: scenarioSubs}
+}
+
+func (c *collector) startVU() *shard {
+	c.activeVUs.Add(1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n := len(c.idle); n > 0 {
+		vu := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		return vu
+	}
+
+	vu := newShard()
+	vu.outputTags = c.outputTags
+	vu.scenarioSubs = c.scenarioSubs
+	c.shards = append(c.shards, vu)
+	return vu
+}
+
+func (c *collector) stopVU(vu *shard) {
+	c.activeVUs.Add(-1)
+
+	c.mu.Lock()
+	c.idle = append(c.idle, vu)
+	c.mu.Unlock()
+}
+
+func (c *collector) addDropped() {
+	c.dropped.Add(1)
+}
+
+// shared returns the counters kept outside the VU shards.
+func (c *collector) shared() counts {
+	return counts{
+		dropped:  int(c.dropped.Load()),
+		sent:     int(c.dataSent.Load()),
+		received: int(c.dataReceived.Load()),
+	}
+}
+
+// aggregate merges every shard into a new one.
+func (c *collector) aggregate() *shard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := newShard()
+	for _, vu := range c.shards {
+		total.merge(vu)
+	}
+	shared := c.shared()
+	total.counts.add(shared)
+	for _, key := range c.scenarioSubs {
+		total.sub(key).counts.add(shared)
+	}
+	return total
+}
+
+// drain takes the raw samples and spans the VUs have kept since the previous
+// call.
+func (c *collector) drain(samples []Sample, spans []Span) ([]Sample, []Span) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, vu := range c.shards {
+		vu.mu.Lock()
+		samples = append(samples, vu.samples...)
+		spans = append(spans, vu.spans...)
+		clear(vu.samples)
+		clear(vu.spans)
+		vu.samples = vu.samples[:0]
+		vu.spans = vu.spans[:0]
+		vu.mu.Unlock()
+	}
+	return samples, spans
+}
+
+// window returns the running counters together with the service and
+// response times recorded since the previous call.
+func (c *collector) window() (counts, *histogram, *histogram) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var current counts
+	durations := &histogram{}
+	responseTimes := &histogram{}
+
+	for _, vu := range c.shards {
+		vu.mu.Lock()
+		current.add(vu.counts)
+		durations.merge(&vu.window)
+		responseTimes.merge(&vu.responseWindow)
+		vu.window.reset()
+		vu.responseWindow.reset()
+		vu.mu.Unlock()
+	}
+	current.add(c.shared())
+
+	return current, durations, responseTimes
+}
+
+func (s *shard) summary(elapsed time.Duration) model.Summary {
+	perSecond := func(n int) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return float64(n) / elapsed.Seconds()
+	}
+
+	latency := s.durations.stats()
+	steps, endpoints := stepResults(s.steps)
+
+	return model.Summary{
+		TotalRequests:     s.total,
+		Success:           s.success,
+		Failure:           s.failure,
+		AvgLatencyMs:      roundMillis(latency.Avg),
+		P90LatencyMs:      roundMillis(latency.P90),
+		P95LatencyMs:      roundMillis(latency.P95),
+		P99LatencyMs:      roundMillis(latency.P99),
+		Latency:           latency,
+		ResponseTime:      s.responseTimes.stats(),
+		RPS:               perSecond(s.total),
+		Iterations:        s.iterations,
+		DroppedIterations: s.dropped,
+		DataSent:          s.sent,
+		DataReceived:      s.received,
+		DataSentRate:      perSecond(s.sent),
+		DataReceivedRate:  perSecond(s.received),
+		Checks:            s.checkResults(),
+		StatusCodes:       s.statusCodes(),
+		Errors:            failureResults(s.failures),
+		Timings:           s.timingStats(),
+		Steps:             steps,
+		Endpoints:         endpoints,
+		Metrics:           customResults(s.custom, elapsed),
+	}
+}
+
+func (s *shard) statusCodes() map[int]int {
+	if len(s.steps) == 0 {
+		return nil
+	}
+
+	statuses := make(map[int]int)
+	for _, stats := range s.steps {
+		for status, n := range stats.statuses {
+			statuses[status] += n
+		}
+	}
+	return statuses
+}
+
+func (s *shard) timingStats() map[string]model.TrendStats {
+	if s.total == 0 {
+		return nil
+	}
+
+	stats := make(map[string]model.TrendStats, timingCount)
+	for i, name := range timingMetrics {
+		stats[name] = s.timings[i].stats()
+	}
+	return stats
+}
+
+func (s *shard) checkResults() []model.CheckResult {
+	if len(s.checkOrder) == 0 {
+		return nil
+	}
+
+	results := make([]model.CheckResult, 0, len(s.checkOrder))
+	for _, name := range s.checkOrder {
+		counts := s.checks[name]
+		results = append(results, model.CheckResult{
+			Name:   name,
+			Passes: counts.passes,
+			Fails:  counts.fails,
+		})
+	}
+	return results
+}
+
+// view captures the aggregated values for threshold evaluation. Declared
+// custom metrics without samples resolve as empty.
+func (s *shard) view(elapsed time.Duration, declared map[string]model.MetricType) metricView {
+	view := metricView{
+		durations: &s.durations,
+		responses: &s.responseTimes,
+		timings:   &s.timings,
+		counts:    s.counts,
+		custom:    byName(s.custom),
+		subs:      s.subs,
+		declared:  declared,
+		elapsed:   elapsed,
+	}
+	for name, kind := range declared {
+		if _, ok := view.custom[name]; !ok {
+			view.custom[name] = &customMetric{name: name, kind: kind}
+		}
+	}
+	for _, counts := range s.checks {
+		view.checkPasses += counts.passes
+		view.checkFails += counts.fails
+	}
+	return view
+}
+
+func roundMillis(ms float64) int64 {
+	return int64(math.Round(ms))
+}
@@ -0,0 +1,331 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,127}$`)
+
+// customAggregations lists the values reported for each custom metric type,
+// following k6's end-of-test summary.
+var customAggregations = map[model.MetricType][]string{
+	model.MetricCounter: {"count", "rate"},
+	model.MetricGauge:   {"value", "min", "max"},
+	model.MetricRate:    {"rate", "passes", "fails"},
+	model.MetricTrend:   {"avg", "min", "med", "max", "p(90)", "p(95)"},
+}
+
+// ValidateMetricName rejects names k6 would not accept and the names of the
+// engine's own metrics.
+func ValidateMetricName(name string) error {
+	if !metricNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+	if builtinMetric(name) {
+		return fmt.Errorf("metric name %q is reserved", name)
+	}
+	return nil
+}
+
+func builtinMetric(name string) bool {
+	switch name {
+	case "http_req_duration", "http_req_response_time", "http_req_failed", "checks", "http_reqs", "iterations",
+		"dropped_iterations", "data_sent", "data_received":
+		return true
+	}
+	return slices.Contains(timingMetrics[:], name)
+}
+
+type customKey struct {
+	name string
+	tags string
+}
+
+func newCustomKey(name string, tags map[string]string) customKey {
+	return customKey{name: name, tags: tagKey(tags)}
+}
+
+type customMetric struct {
+	name string
+	kind model.MetricType
+	tags map[string]string
+
+	count   int
+	sum     float64
+	nonZero int
+	min     float64
+	max     float64
+	last    float64
+	lastAt  time.Time
+
+	// trend holds trend samples in thousandths, so percentiles keep three
+	// decimals. Negative samples count as zero.
+	trend histogram
+}
+
+func (m *customMetric) add(value float64, at time.Time) {
+	if m.count == 0 || value < m.min {
+		m.min = value
+	}
+	if m.count == 0 || value > m.max {
+		m.max = value
+	}
+	if !at.Before(m.lastAt) {
+		m.last, m.lastAt = value, at
+	}
+
+	m.count++
+	m.sum += value
+	if value != 0 {
+		m.nonZero++
+	}
+	if m.kind == model.MetricTrend {
+		m.trend.add(int64(math.Round(value * 1000)))
+	}
+}
+
+func (m *customMetric) merge(other *customMetric) {
+	if other.count == 0 {
+		return
+	}
+
+	if m.count == 0 || other.min < m.min {
+		m.min = other.min
+	}
+	if m.count == 0 || other.max > m.max {
+		m.max = other.max
+	}
+	if !other.lastAt.Before(m.lastAt) {
+		m.last, m.lastAt = other.last, other.lastAt
+	}
+
+	m.count += other.count
+	m.sum += other.sum
+	m.nonZero += other.nonZero
+	m.trend.merge(&other.trend)
+}
+
+func (m *customMetric) value(aggregation string, elapsed time.Duration) (float64, error) {
+	switch m.kind {
+	case model.MetricCounter:
+		switch aggregation {
+		case "count":
+			return m.sum, nil
+		case "rate":
+			if elapsed <= 0 {
+				return 0, nil
+			}
+			return m.sum / elapsed.Seconds(), nil
+		}
+	case model.MetricGauge:
+		switch aggregation {
+		case "value":
+			return m.last, nil
+		case "min":
+			return m.min, nil
+		case "max":
+			return m.max, nil
+		}
+	case model.MetricRate:
+		switch aggregation {
+		case "rate":
+			if m.count == 0 {
+				return 0, nil
+			}
+			return float64(m.nonZero) / float64(m.count), nil
+		case "passes":
+			return float64(m.nonZero), nil
+		case "fails":
+			return float64(m.count - m.nonZero), nil
+		}
+	case model.MetricTrend:
+		return trendValue(&m.trend, aggregation)
+	}
+
+	return 0, fmt.Errorf("%w: %s for %s", errUnsupportedAggr, aggregation, m.name)
+}
+
+func customResults(metrics map[customKey]*customMetric, elapsed time.Duration) []model.MetricResult {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	keys := slices.SortedFunc(maps.Keys(metrics), func(a, b customKey) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.tags, b.tags)
+	})
+
+	results := make([]model.MetricResult, 0, len(keys))
+	for _, key := range keys {
+		metric := metrics[key]
+		values := make(map[string]float64)
+		for _, aggregation := range customAggregations[metric.kind] {
+			values[aggregation], _ = metric.value(aggregation, elapsed)
+		}
+
+		results = append(results, model.MetricResult{
+			Name:   metric.name,
+			Type:   metric.kind,
+			Tags:   metric.tags,
+			Values: values,
+		})
+	}
+	return results
+}
+
+// byName merges the tagged series of each custom metric.
+func byName(metrics map[customKey]*customMetric) map[string]*customMetric {
+	merged := make(map[string]*customMetric)
+	for _, metric := range metrics {
+		total, ok := merged[metric.name]
+		if !ok {
+			total = &customMetric{name: metric.name, kind: metric.kind}
+			merged[metric.name] = total
+		}
+		total.merge(metric)
+	}
+	return merged
+}
+
+// stepMetric is a step metric with its series key and the full tag set its
+// samples carry for sub-metric thresholds.
+type stepMetric struct {
+	model.StepMetric
+	key  customKey
+	tags map[string]string
+}
+
+func describeMetrics(step model.Step, tags map[string]string) []stepMetric {
+	metrics := make([]stepMetric, len(step.Metrics))
+	for i, metric := range step.Metrics {
+		sampleTags := maps.Clone(tags)
+		maps.Copy(sampleTags, metric.Tags)
+
+		metrics[i] = stepMetric{
+			StepMetric: metric,
+			key:        newCustomKey(metric.Name, metric.Tags),
+			tags:       sampleTags,
+		}
+	}
+	return metrics
+}
+
+func metricsNeedBody(metrics []model.StepMetric) bool {
+	return slices.ContainsFunc(metrics, func(metric model.StepMetric) bool {
+		return metric.Source == model.SourceJSONPath
+	})
+}
+
+// sampleValue resolves the sample a step metric records for one request. It
+// reports false when the source has no numeric value, such as a missing JSON
+// field or a request that got no response.
+func sampleValue(
+	metric model.StepMetric,
+	resp *http.Response,
+	body []byte,
+	duration time.Duration,
+	outcomes []checkOutcome,
+) (float64, bool) {
+	switch metric.Source {
+	case model.SourceJSONPath:
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return 0, false
+		}
+		value, ok := lookupJSON(doc, metric.Path)
+		if !ok {
+			return 0, false
+		}
+		return jsonNumber(value)
+	case model.SourceCheck:
+		for _, outcome := range outcomes {
+			if outcome.name == metric.Check {
+				if outcome.passed {
+					return 1, true
+				}
+				return 0, true
+			}
+		}
+		return 0, false
+	case model.SourceDuration:
+		return float64(duration.Microseconds()) / 1000, true
+	case model.SourceStatus:
+		if resp == nil {
+			return 0, false
+		}
+		return float64(resp.StatusCode), true
+	}
+
+	if metric.Value != nil {
+		return *metric.Value, true
+	}
+	return 1, true
+}
+
+func jsonNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// declaredMetrics returns the type of every custom metric the scripts' steps
+// record.
+func declaredMetrics(scripts map[string]*model.Script) (map[string]model.MetricType, error) {
+	declared := make(map[string]model.MetricType)
+	for _, id := range slices.Sorted(maps.Keys(scripts)) {
+		for _, step := range scripts[id].Steps {
+			for _, metric := range step.Metrics {
+				if kind, ok := declared[metric.Name]; ok && kind != metric.Type {
+					return nil, fmt.Errorf("metric %s is declared as both %s and %s", metric.Name, kind, metric.Type)
+				}
+				declared[metric.Name] = metric.Type
+			}
+		}
+	}
+	return declared, nil
+}
+
+// ValidateThresholds checks thresholds against the metrics the run records:
+// the built-in ones and the custom metrics declared by the scripts' steps.
+func ValidateThresholds(thresholds []model.Threshold, scripts map[string]*model.Script) error {
+	declared, err := declaredMetrics(scripts)
+	if err != nil {
+		return err
+	}
+	view := newShard().view(0, declared)
+
+	for _, threshold := range thresholds {
+		normalized, err := NormalizeThreshold(threshold)
+		if err == nil {
+			_, err = view.value(normalized.Metric, normalized.Aggregation)
+		}
+		if err != nil {
+			return errors.New("threshold " + threshold.Metric + ": " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+var idSegmentPattern = regexp.MustCompile(`^(?:[0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+type stepKey struct {
+	scriptID string
+	index    int
+}
+
+// stepMeta is a script step as run by one scenario: its identity in the
+// per-step breakdown, the tags its samples carry and the threshold
+// sub-metrics they may count towards.
+type stepMeta struct {
+	stepKey
+	step    model.Step
+	name    string
+	method  string
+	url     string
+	tags    map[string]string
+	subs    []subMetric
+	metrics []stepMetric
+}
+
+func describeSteps(script *model.Script, tags map[string]string, subs []subMetric) []stepMeta {
+	steps := make([]stepMeta, len(script.Steps))
+	for i, step := range script.Steps {
+		stepTags := stepTags(tags, step)
+		steps[i] = stepMeta{
+			stepKey: stepKey{scriptID: script.ID, index: i},
+			step:    step,
+			name:    EndpointName(step),
+			method:  strings.ToUpper(step.Method),
+			url:     NormalizeURL(step.URL),
+			tags:    stepTags,
+			subs:    subs,
+			metrics: describeMetrics(step, stepTags),
+		}
+	}
+	return steps
+}
+
+// EndpointName returns the name a step's requests are grouped under: the
+// step's own name or name tag, or its normalized URL.
+func EndpointName(step model.Step) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	if name := step.Tags["name"]; name != "" {
+		return name
+	}
+	return NormalizeURL(step.URL)
+}
+
+// NormalizeURL drops the query and fragment and replaces path segments that
+// look like identifiers (numbers, UUIDs, long hex strings) with ":id", so
+// requests to the same endpoint group together.
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if idSegmentPattern.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.User = nil
+	return u.String()
+}
+
+type requestStats struct {
+	requests  int
+	failures  int
+	durations histogram
+	statuses  map[int]int
+}
+
+// record counts one request; status is 0 when no response arrived.
+func (r *requestStats) record(duration time.Duration, status int, ok bool) {
+	r.requests++
+	if !ok {
+		r.failures++
+	}
+	r.durations.record(duration)
+
+	if r.statuses == nil {
+		r.statuses = make(map[int]int)
+	}
+	r.statuses[status]++
+}
+
+func (r *requestStats) merge(other *requestStats) {
+	r.requests += other.requests
+	r.failures += other.failures
+	r.durations.merge(&other.durations)
+
+	if r.statuses == nil {
+		r.statuses = make(map[int]int, len(other.statuses))
+	}
+	for status, n := range other.statuses {
+		r.statuses[status] += n
+	}
+}
+
+func (r *requestStats) result() model.RequestStats {
+	return model.RequestStats{
+		Requests:    r.requests,
+		Failures:    r.failures,
+		Latency:     r.durations.stats(),
+		StatusCodes: r.statuses,
+	}
+}
+
+type stepStats struct {
+	stepMeta
+	requestStats
+}
+
+// stepResults lists steps in script and step order, along with the same
+// requests grouped by method and endpoint name in order of first appearance.
+func stepResults(steps map[stepKey]*stepStats) ([]model.StepResult, []model.EndpointResult) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	sorted := slices.SortedFunc(maps.Values(steps), func(a, b *stepStats) int {
+		if c := strings.Compare(a.scriptID, b.scriptID); c != 0 {
+			return c
+		}
+		return a.index - b.index
+	})
+
+	type endpointKey struct{ method, name string }
+	endpoints := make(map[endpointKey]*requestStats)
+	var order []endpointKey
+
+	results := make([]model.StepResult, 0, len(sorted))
+	for _, s := range sorted {
+		results = append(results, model.StepResult{
+			ScriptID:     s.scriptID,
+			Index:        s.index,
+			Name:         s.name,
+			Method:       s.method,
+			URL:          s.url,
+			RequestStats: s.result(),
+		})
+
+		key := endpointKey{s.method, s.name}
+		endpoint, ok := endpoints[key]
+		if !ok {
+			endpoint = &requestStats{}
+			endpoints[key] = endpoint
+			order = append(order, key)
+		}
+		endpoint.merge(&s.requestStats)
+	}
+
+	grouped := make([]model.EndpointResult, 0, len(order))
+	for _, key := range order {
+		grouped = append(grouped, model.EndpointResult{
+			Name:         key.name,
+			Method:       key.method,
+			RequestStats: endpoints[key].result(),
+		})
+	}
+	return results, grouped
+}
@@ -0,0 +1,234 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	rampInterval       = 100 * time.Millisecond
+	defaultMaxDuration = 10 * time.Minute
+)
+
+// iterationFunc runs one iteration on a VU; cancelling ctx cuts it short.
+// Open-model executors pass the time the iteration was scheduled to start;
+// closed loops pass the zero time.
+type iterationFunc func(ctx context.Context, vu *shard, scheduled time.Time)
+
+func runExecutor(ctx context.Context, config model.ExecutorConfig, iterate iterationFunc, metrics *collector) {
+	switch ResolveExecutor(config) {
+	case model.RampingVUs:
+		runRampingVUs(ctx, metrics, config.Stages, iterate)
+	case model.ConstantArrivalRate, model.RampingArrivalRate:
+		startRate, stages := arrivalStages(config)
+		preAllocated, maxVUs := VUPool(config)
+		runArrivalRate(ctx, metrics, startRate, stages, preAllocated, maxVUs, iterate)
+	case model.PerVUIterations:
+		runPerVUIterations(ctx, metrics, max(config.VUs, 1), config.Iterations, maxDuration(config), iterate)
+	case model.SharedIterations:
+		runSharedIterations(ctx, metrics, max(config.VUs, 1), config.Iterations, maxDuration(config), iterate)
+	default:
+		runConstantVUs(ctx, metrics, config.VUs, time.Duration(config.Duration)*time.Second, iterate)
+	}
+}
+
+func ResolveExecutor(config model.ExecutorConfig) model.Executor {
+	if config.Executor != "" {
+		return config.Executor
+	}
+	if len(config.Stages) > 0 {
+		return model.RampingVUs
+	}
+	return model.ConstantVUs
+}
+
+func VUPool(config model.ExecutorConfig) (int, int) {
+	preAllocated := config.PreAllocatedVUs
+	if preAllocated <= 0 {
+		preAllocated = max(config.VUs, 1)
+	}
+
+	maxVUs := max(config.MaxVUs, preAllocated)
+	return preAllocated, maxVUs
+}
+
+func maxDuration(config model.ExecutorConfig) time.Duration {
+	if config.MaxDuration > 0 {
+		return time.Duration(config.MaxDuration) * time.Second
+	}
+	return defaultMaxDuration
+}
+
+func arrivalStages(config model.ExecutorConfig) (float64, []rateStage) {
+	unit := time.Second
+	if config.TimeUnit != "" {
+		if d, err := time.ParseDuration(config.TimeUnit); err == nil && d > 0 {
+			unit = d
+		}
+	}
+	perSecond := func(rate int) float64 {
+		return float64(rate) / unit.Seconds()
+	}
+
+	if ResolveExecutor(config) == model.ConstantArrivalRate {
+		rate := perSecond(config.Rate)
+		return rate, []rateStage{{
+			duration: time.Duration(config.Duration) * time.Second,
+			target:   rate,
+		}}
+	}
+
+	stages := make([]rateStage, 0, len(config.Stages))
+	for _, stage := range config.Stages {
+		stages = append(stages, rateStage{
+			duration: time.Duration(stage.Duration) * time.Second,
+			target:   perSecond(stage.Target),
+		})
+	}
+	return perSecond(config.StartRate), stages
+}
+
+func runConstantVUs(ctx context.Context, metrics *collector, vus int, duration time.Duration, iterate iterationFunc) {
+	endAt := time.Now().Add(duration)
+
+	wg := sync.WaitGroup{}
+
+	for range vus {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			vu := metrics.startVU()
+			defer metrics.stopVU(vu)
+
+			for ctx.Err() == nil && time.Now().Before(endAt) {
+				iterate(ctx, vu, time.Time{})
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// runRampingVUs linearly moves the number of running VUs towards each
+// stage's target. Retired VUs finish their current iteration before exiting.
+// Cancelling ctx retires every VU at once.
+func runRampingVUs(ctx context.Context, metrics *collector, stages []model.Stage, iterate iterationFunc) {
+	wg := sync.WaitGroup{}
+	var stops []chan struct{}
+
+	scale := func(target int) {
+		for len(stops) < target {
+			stop := make(chan struct{})
+			stops = append(stops, stop)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				vu := metrics.startVU()
+				defer metrics.stopVU(vu)
+
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ctx.Done():
+						return
+					default:
+						iterate(ctx, vu, time.Time{})
+					}
+				}
+			}()
+		}
+
+		for len(stops) > target {
+			last := len(stops) - 1
+			close(stops[last])
+			stops = stops[:last]
+		}
+	}
+
+	current := 0
+	ticker := time.NewTicker(rampInterval)
+	defer ticker.Stop()
+
+stages:
+	for _, stage := range stages {
+		from := current
+		duration := time.Duration(stage.Duration) * time.Second
+		stageStart := time.Now()
+
+		for elapsed := time.Since(stageStart); elapsed < duration; elapsed = time.Since(stageStart) {
+			progress := float64(elapsed) / float64(duration)
+			scale(from + int(float64(stage.Target-from)*progress))
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break stages
+			}
+		}
+
+		scale(stage.Target)
+		current = stage.Target
+	}
+
+	scale(0)
+	wg.Wait()
+}
+
+// runPerVUIterations runs iterations on each VU. maxDuration cancels the
+// iterations still running when it passes, along with their requests.
+func runPerVUIterations(ctx context.Context, metrics *collector, vus, iterations int, maxDuration time.Duration, iterate iterationFunc) {
+	ctx, cancel := context.WithTimeout(ctx, maxDuration)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+
+	for range vus {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			vu := metrics.startVU()
+			defer metrics.stopVU(vu)
+
+			for i := 0; i < iterations && ctx.Err() == nil; i++ {
+				iterate(ctx, vu, time.Time{})
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// runSharedIterations shares iterations between the VUs, with the same
+// maxDuration cap as runPerVUIterations.
+func runSharedIterations(ctx context.Context, metrics *collector, vus, iterations int, maxDuration time.Duration, iterate iterationFunc) {
+	ctx, cancel := context.WithTimeout(ctx, maxDuration)
+	defer cancel()
+
+	remaining := int64(iterations)
+
+	wg := sync.WaitGroup{}
+
+	for range vus {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			vu := metrics.startVU()
+			defer metrics.stopVU(vu)
+
+			for ctx.Err() == nil && atomic.AddInt64(&remaining, -1) >= 0 {
+				iterate(ctx, vu, time.Time{})
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	failureTimeout           = "timeout"
+	failureDNS               = "dns"
+	failureConnectionRefused = "connection_refused"
+	failureConnectionReset   = "connection_reset"
+	failureTLS               = "tls"
+	failureNetwork           = "network"
+	failureInvalidRequest    = "invalid_request"
+)
+
+// requestFailure is why a request failed: a network error category, or
+// "http_<status>" for error responses.
+type requestFailure struct {
+	category string
+	message  string
+}
+
+// classifyFailure returns nil for requests that got a response below 400.
+func classifyFailure(step stepMeta, err error, resp *http.Response) *requestFailure {
+	if err == nil {
+		if resp.StatusCode < 400 {
+			return nil
+		}
+		return &requestFailure{
+			category: fmt.Sprintf("http_%d", resp.StatusCode),
+			message:  fmt.Sprintf("%s %s: %s", step.method, step.url, resp.Status),
+		}
+	}
+
+	return &requestFailure{
+		category: errorCategory(err),
+		message:  err.Error(),
+	}
+}
+
+func errorCategory(err error) string {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || urlErr.Op == "parse" {
+		// client.Do wraps every error it returns in a *url.Error, so anything
+		// else failed while building the request.
+		return failureInvalidRequest
+	}
+
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var headerErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return failureDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return failureTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return failureConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return failureConnectionReset
+	case errors.As(err, &certErr), errors.As(err, &headerErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr):
+		return failureTLS
+	}
+	return failureNetwork
+}
+
+type failureStats struct {
+	count     int
+	sample    string
+	firstSeen time.Time
+}
+
+func (f *failureStats) merge(other *failureStats) {
+	if f.count == 0 || other.firstSeen.Before(f.firstSeen) {
+		f.sample = other.sample
+		f.firstSeen = other.firstSeen
+	}
+	f.count += other.count
+}
+
+// failureResults lists failure categories, most frequent first.
+func failureResults(failures map[string]*failureStats) []model.ErrorResult {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	results := make([]model.ErrorResult, 0, len(failures))
+	for _, category := range slices.Sorted(maps.Keys(failures)) {
+		stats := failures[category]
+		results = append(results, model.ErrorResult{
+			Category:  category,
+			Count:     stats.count,
+			Sample:    stats.sample,
+			FirstSeen: stats.firstSeen,
+		})
+	}
+
+	slices.SortStableFunc(results, func(a, b model.ErrorResult) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return results
+}
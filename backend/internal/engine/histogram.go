@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	histogramSubBucketBits = 8
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramHalfBuckets   = histogramSubBuckets / 2
+)
+
+// histogram is an HDR-style log-linear histogram of microsecond durations.
+// Values below histogramSubBuckets are counted exactly; above that every
+// power-of-two range is split into histogramHalfBuckets linear buckets, which
+// keeps any reported value within 0.4% of the recorded one. Memory depends
+// only on the largest value seen, never on the number of samples.
+type histogram struct {
+	buckets []int64
+	count   int64
+	sum     int64
+	min     int64
+	max     int64
+}
+
+func bucketIndex(us int64) int {
+	if us < histogramSubBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(uint64(us)) - histogramSubBucketBits
+	return shift*histogramHalfBuckets + int(us>>shift)
+}
+
+// bucketValue returns the midpoint of the values counted in bucket index.
+func bucketValue(index int) int64 {
+	if index < histogramSubBuckets {
+		return int64(index)
+	}
+	shift := index/histogramHalfBuckets - 1
+	lower := int64(index-shift*histogramHalfBuckets) << shift
+	return lower + (int64(1)<<shift-1)/2
+}
+
+func (h *histogram) grow(size int) {
+	if size > len(h.buckets) {
+		h.buckets = append(h.buckets, make([]int64, size-len(h.buckets))...)
+	}
+}
+
+func (h *histogram) record(d time.Duration) {
+	h.add(d.Microseconds())
+}
+
+func (h *histogram) add(us int64) {
+	us = max(us, 0)
+	index := bucketIndex(us)
+	h.grow(index + 1)
+	h.buckets[index]++
+
+	if h.count == 0 || us < h.min {
+		h.min = us
+	}
+	if us > h.max {
+		h.max = us
+	}
+	h.count++
+	h.sum += us
+}
+
+func (h *histogram) merge(other *histogram) {
+	if other.count == 0 {
+		return
+	}
+
+	h.grow(len(other.buckets))
+	for i, n := range other.buckets {
+		h.buckets[i] += n
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.count += other.count
+	h.sum += other.sum
+}
+
+func (h *histogram) reset() {
+	clear(h.buckets)
+	h.count, h.sum, h.min, h.max = 0, 0, 0, 0
+}
+
+// percentile returns the p-th percentile (0..100) in milliseconds.
+func (h *histogram) percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := max(int64(math.Ceil(p/100*float64(h.count))), 1)
+	seen := int64(0)
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			return millis(min(max(bucketValue(i), h.min), h.max))
+		}
+	}
+	return millis(h.max)
+}
+
+func (h *histogram) mean() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.count) / 1000
+}
+
+func (h *histogram) stats() model.TrendStats {
+	return model.TrendStats{
+		Count: int(h.count),
+		Avg:   h.mean(),
+		Min:   millis(h.min),
+		Med:   h.percentile(50),
+		Max:   millis(h.max),
+		P90:   h.percentile(90),
+		P95:   h.percentile(95),
+		P99:   h.percentile(99),
+		P999:  h.percentile(99.9),
+	}
+}
+
+func millis(us int64) float64 {
+	return float64(us) / 1000
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// maxRelativeError is the bound the histogram's doc comment promises: half a
+// bucket of the 128 buckets each power of two above histogramSubBuckets gets.
+const maxRelativeError = 1.0 / histogramSubBuckets
+
+func TestBucketsAreContiguous(t *testing.T) {
+	first := int64(0)
+	for v := int64(1); v <= 1<<22; v++ {
+		prev, index := bucketIndex(v-1), bucketIndex(v)
+		if index == prev {
+			continue
+		}
+		if index != prev+1 {
+			t.Fatalf("bucketIndex(%d) = %d after bucketIndex(%d) = %d, want consecutive buckets", v, index, v-1, prev)
+		}
+
+		checkBucketValue(t, prev, first, v-1)
+		first = v
+	}
+}
+
+func TestBucketsAcrossPowersOfTwo(t *testing.T) {
+	for k := histogramSubBucketBits; k < 62; k++ {
+		edge := int64(1) << k
+		if got, want := bucketIndex(edge), bucketIndex(edge-1)+1; got != want {
+			t.Errorf("bucketIndex(2^%d) = %d, want %d", k, got, want)
+		}
+		if got, want := bucketIndex(edge+1), bucketIndex(edge); got != want {
+			t.Errorf("bucketIndex(2^%d+1) = %d, want %d", k, got, want)
+		}
+
+		shift := k - histogramSubBucketBits + 1
+		width := int64(1) << shift
+		checkBucketValue(t, bucketIndex(edge), edge, edge+width-1)
+		checkBucketValue(t, bucketIndex(edge-1), edge-width/2, edge-1)
+	}
+}
+
+// checkBucketValue checks that bucket index holds the values first to last
+// and reports a value within maxRelativeError of each of them.
+func checkBucketValue(t *testing.T, index int, first, last int64) {
+	t.Helper()
+
+	if bucketIndex(first) != index || bucketIndex(last) != index {
+		t.Fatalf("bucket %d: want values %d..%d, got bucketIndex %d..%d", index, first, last, bucketIndex(first), bucketIndex(last))
+	}
+	value := bucketValue(index)
+	if value < first || value > last {
+		t.Fatalf("bucketValue(%d) = %d, want within %d..%d", index, value, first, last)
+	}
+	for _, v := range []int64{first, last} {
+		if err := math.Abs(float64(value-v)) / float64(v+1); err > maxRelativeError {
+			t.Fatalf("bucketValue(%d) = %d is %.4f%% off %d, want at most %.4f%%", index, value, err*100, v, maxRelativeError*100)
+		}
+	}
+}
+
+func TestPercentileWithinError(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	tests := []struct {
+		name   string
+		sample func(i int) int64
+	}{
+		{"uniform", func(i int) int64 { return rng.Int64N(1_000_000) }},
+		{"sequential", func(i int) int64 { return int64(i) }},
+		{"exponential", func(i int) int64 { return int64(rng.ExpFloat64() * 20_000) }},
+		{"bimodal", func(i int) int64 {
+			if rng.IntN(100) == 0 {
+				return 2_000_000 + rng.Int64N(500_000)
+			}
+			return 5_000 + rng.Int64N(1_000)
+		}},
+		{"small", func(i int) int64 { return rng.Int64N(histogramSubBuckets) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const n = 200_000
+			h := &histogram{}
+			raw := make([]int64, n)
+			for i := range raw {
+				raw[i] = tt.sample(i)
+				h.record(time.Duration(raw[i]) * time.Microsecond)
+			}
+			slices.Sort(raw)
+
+			for _, p := range []float64{0, 50, 90, 95, 99, 99.9, 100} {
+				rank := max(int(math.Ceil(p/100*n)), 1)
+				want := millis(raw[rank-1])
+				got := h.percentile(p)
+				if math.Abs(got-want) > want*maxRelativeError+1e-9 {
+					t.Errorf("percentile(%v) = %vms, want %vms within %.2f%%", p, got, want, maxRelativeError*100)
+				}
+			}
+
+			if got, want := h.stats().Min, millis(raw[0]); got != want {
+				t.Errorf("min = %v, want %v", got, want)
+			}
+			if got, want := h.stats().Max, millis(raw[n-1]); got != want {
+				t.Errorf("max = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeMatchesSingleHistogram(t *testing.T) {
+	whole, merged := &histogram{}, &histogram{}
+	parts := []*histogram{{}, {}, {}}
+	for i := range 30_000 {
+		d := time.Duration(i*i%1_000_003) * time.Microsecond
+		whole.record(d)
+		parts[i%len(parts)].record(d)
+	}
+	for _, part := range parts {
+		merged.merge(part)
+	}
+
+	if got, want := merged.stats(), whole.stats(); got != want {
+		t.Errorf("merged stats = %+v, want %+v", got, want)
+	}
+}
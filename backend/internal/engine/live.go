@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	snapshotInterval  = time.Second
+	subscriberBacklog = 16
+)
+
+// Broadcaster fans the per-second snapshots of one run out to any number of
+// subscribers. Publishing never blocks: a subscriber that falls behind misses
+// snapshots instead of slowing the run down.
+type Broadcaster struct {
+	mu        sync.RWMutex
+	subs      map[chan model.Snapshot]struct{}
+	latest    *model.Snapshot
+	closed    bool
+	scenarios func() map[string]model.ScenarioResult
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subs: make(map[chan model.Snapshot]struct{}),
+	}
+}
+
+// Subscribe returns a channel of snapshots that is closed when the run ends,
+// and a function that cancels the subscription.
+func (b *Broadcaster) Subscribe() (<-chan model.Snapshot, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan model.Snapshot, subscriberBacklog)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.latest != nil {
+		ch <- *b.latest
+	}
+	b.subs[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broadcaster) Latest() (model.Snapshot, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.latest == nil {
+		return model.Snapshot{}, false
+	}
+	return *b.latest, true
+}
+
+// Scenarios returns the per-scenario metrics recorded so far, or false before
+// the run has started.
+func (b *Broadcaster) Scenarios() (map[string]model.ScenarioResult, bool) {
+	b.mu.RLock()
+	scenarios := b.scenarios
+	b.mu.RUnlock()
+
+	if scenarios == nil {
+		return nil, false
+	}
+	return scenarios(), true
+}
+
+func (b *Broadcaster) track(scenarios func() map[string]model.ScenarioResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scenarios = scenarios
+}
+
+func (b *Broadcaster) publish(snapshot model.Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latest = &snapshot
+	for ch := range b.subs {
+		select {
+		case ch <- snapshot:
+		default:
+		}
+	}
+}
+
+func (b *Broadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+type sampler struct {
+	collectors map[string]*collector
+	output     *outputWriter
+	startedAt  time.Time
+	last       time.Time
+	prev       counts
+	series     []model.Snapshot
+}
+
+// newSampler returns a sampler of the collectors. output, when set, is sent
+// the raw samples and each snapshot.
+func newSampler(collectors map[string]*collector, output *outputWriter, startedAt time.Time) *sampler {
+	return &sampler{
+		collectors: collectors,
+		output:     output,
+		startedAt:  startedAt,
+		last:       startedAt,
+	}
+}
+
+func (s *sampler) sample(now time.Time) model.Snapshot {
+	var current counts
+	latencies := &histogram{}
+	responseTimes := &histogram{}
+	vus := int64(0)
+
+	for _, c := range s.collectors {
+		cumulative, window, responseWindow := c.window()
+		current.add(cumulative)
+		latencies.merge(window)
+		responseTimes.merge(responseWindow)
+		vus += c.activeVUs.Load()
+	}
+
+	requests := current.total - s.prev.total
+	failures := current.failure - s.prev.failure
+	interval := now.Sub(s.last).Seconds()
+
+	snapshot := model.Snapshot{
+		Time:              now,
+		ElapsedSec:        now.Sub(s.startedAt).Seconds(),
+		ActiveVUs:         int(vus),
+		TotalRequests:     current.total,
+		Iterations:        current.iterations,
+		DroppedIterations: current.dropped,
+		DataSent:          current.sent,
+		DataReceived:      current.received,
+		AvgLatencyMs:      roundMillis(latencies.mean()),
+		P50LatencyMs:      roundMillis(latencies.percentile(50)),
+		P90LatencyMs:      roundMillis(latencies.percentile(90)),
+		P95LatencyMs:      roundMillis(latencies.percentile(95)),
+		P99LatencyMs:      roundMillis(latencies.percentile(99)),
+		P95ResponseTimeMs: roundMillis(responseTimes.percentile(95)),
+		P99ResponseTimeMs: roundMillis(responseTimes.percentile(99)),
+	}
+	if interval > 0 {
+		snapshot.RPS = float64(requests) / interval
+	}
+	if requests > 0 {
+		snapshot.ErrorRate = float64(failures) / float64(requests)
+	}
+
+	s.prev = current
+	s.last = now
+	return snapshot
+}
+
+// sampleLoop publishes a snapshot every interval until ctx is done, then a
+// final one covering the tail of the run.
+func (s *sampler) sampleLoop(ctx context.Context, live *Broadcaster) {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.emit(live, now)
+		case <-ctx.Done():
+			s.emit(live, time.Now())
+			return
+		}
+	}
+}
+
+// emit publishes a snapshot, keeps it in the run's time series and hands it
+// to the outputs along with the raw samples and spans taken since the
+// previous one.
+func (s *sampler) emit(live *Broadcaster, now time.Time) {
+	snapshot := s.sample(now)
+
+	if s.output != nil {
+		var prev model.Snapshot
+		if n := len(s.series); n > 0 {
+			prev = s.series[n-1]
+		}
+
+		var samples []Sample
+		var spans []Span
+		for _, c := range s.collectors {
+			samples, spans = c.drain(samples, spans)
+		}
+		samples = append(samples, aggregateSamples(prev, snapshot)...)
+		s.output.write(outputBatch{samples: samples, spans: spans, snapshot: snapshot})
+	}
+
+	s.series = append(s.series, snapshot)
+	live.publish(snapshot)
+}
@@ -1,121 +1,298 @@
 package engine
 
 import (
+	"context"
+	"io"
 	"net/http"
-	"sort"
+	"net/url"
 	"sync"
 	"time"
-	
+
 	"k6clone/internal/model"
 )
 
+const DefaultScenario = "default"
+
 type LoadEngine struct {}
 
 func NewLoadEngine() *LoadEngine {
 	return &LoadEngine{}
 }
 
+// Run executes every scenario of config until it completes or ctx is
+// cancelled. A cancelled run still returns the metrics gathered so far.
+// Per-second snapshots are published to live, which is closed on return, and
+// written to outputs along with the raw samples. Run closes the outputs.
 func (e *LoadEngine) Run(
-	script *model.Script,
+	ctx context.Context,
+	scripts map[string]*model.Script,
 	config model.TestConfig,
+	live *Broadcaster,
+	outputs []Output,
 ) model.TestResult {
 
-	var mu sync.Mutex
-
-	var total, success, failure int
-	var latencies []int64
-	var iterations int
+	runCtx, abortRun := context.WithCancel(ctx)
+	defer abortRun()
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	var abortOnce sync.Once
+	var abortReason string
+	abort := func(reason string) {
+		abortOnce.Do(func() {
+			abortReason = reason
+			abortRun()
+		})
 	}
 
-	startedAt := time.Now()
-	endAt := startedAt.Add(time.Duration(config.Duration) * time.Second)
+	thresholds := normalizeThresholds(config.Thresholds)
+	declared, _ := declaredMetrics(scripts)
+	subs := subMetrics(thresholds)
+	tracing := tracingEnabled(config.Outputs)
+	scenarios := Scenarios(config)
+	metrics := make(map[string]*collector, len(scenarios))
+	started := make(map[string]time.Time, len(scenarios))
+	elapsed := make(map[string]time.Duration, len(scenarios))
 
+	var mu sync.Mutex
 	wg := sync.WaitGroup{}
+	startedAt := time.Now()
 
-	for vu := 0; vu < config.VUs; vu++ {
-		wg.Add(1)
+	var output *outputWriter
+	if len(outputs) > 0 {
+		output = newOutputWriter(outputs)
+	}
+
+	for name, scenario := range scenarios {
+		tags := scenarioTags(config, name, scenario)
+		var outputTags map[string]string
+		if output != nil {
+			outputTags = tags
+		}
+		scenarioMetrics := newCollector(outputTags, scenarioSubs(subs, tags))
+		metrics[name] = scenarioMetrics
 
+		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			for time.Now().Before(endAt) {
-				iterations++
+			if !sleepUntil(runCtx, startedAt.Add(time.Duration(scenario.StartTime)*time.Second)) {
+				return
+			}
 
-				for _, step := range script.Steps {
-					start := time.Now()
+			client := newClient(scenarioMetrics)
+			defer client.CloseIdleConnections()
 
-					req, _ := http.NewRequest(step.Method, step.URL, nil)
-					resp, err := client.Do(req)
-					latency := time.Since(start).Milliseconds()
+			scenarioStart := time.Now()
+			mu.Lock()
+			started[name] = scenarioStart
+			mu.Unlock()
 
-					mu.Lock()
-					total++
-					latencies = append(latencies, latency)
+			steps := describeSteps(scripts[scenario.ScriptID], tags, subs)
+			iterate := newIteration(client, steps, config.DiscardResponseBodies, tracing)
+			runExecutor(runCtx, scenario.ExecutorConfig, iterate, scenarioMetrics)
 
-					if err == nil && resp != nil && resp.StatusCode < 400 {
-						success++
-					} else {
-						failure++
-					}
-					mu.Unlock()
+			mu.Lock()
+			elapsed[name] = time.Since(scenarioStart)
+			mu.Unlock()
+		}()
+	}
 
-					if resp != nil {
-						resp.Body.Close()
-					}
-				}
+	// scenarioResults summarizes each scenario; one still running is
+	// summarized over the time it has run so far.
+	scenarioResults := func() map[string]model.ScenarioResult {
+		mu.Lock()
+		defer mu.Unlock()
+
+		results := make(map[string]model.ScenarioResult, len(scenarios))
+		for name, scenario := range scenarios {
+			duration, ok := elapsed[name]
+			if start, running := started[name]; !ok && running {
+				duration = time.Since(start)
 			}
-		}()
+
+			results[name] = model.ScenarioResult{
+				ScriptID: scenario.ScriptID,
+				Executor: ResolveExecutor(scenario.ExecutorConfig),
+				Tags:     scenario.Tags,
+				Summary:  metrics[name].aggregate().summary(duration),
+			}
+		}
+		return results
 	}
+	live.track(scenarioResults)
+
+	sampler := newSampler(metrics, output, startedAt)
+	sampling, stopSampling := context.WithCancel(context.Background())
+	monitors := sync.WaitGroup{}
+	monitors.Go(func() {
+		sampler.sampleLoop(sampling, live)
+	})
+	monitors.Go(func() {
+		view := func(elapsed time.Duration) metricView {
+			return mergeCollectors(metrics).view(elapsed, declared)
+		}
+		watchThresholds(sampling, thresholds, view, startedAt, abort)
+	})
 
 	wg.Wait()
 
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i] < latencies[j]
-	})
+	stopSampling()
+	monitors.Wait()
+	live.close()
 
-	avgLatency := int64(0)
-	if total > 0 {
-		sum := int64(0)
-		for _, l := range latencies {
-			sum += l
-		}
-		avgLatency = sum / int64(total)
+	var outputErrors []string
+	if output != nil {
+		outputErrors = output.close()
 	}
 
-	p90 := percentile(latencies, 90)
-	p95 := percentile(latencies, 95)
-	p99 := percentile(latencies, 99)
+	total := mergeCollectors(metrics)
+	results := scenarioResults()
 
-	durationSec := time.Since(startedAt).Seconds()
-	rps := float64(total) / durationSec
+	runDuration := time.Since(startedAt)
+	thresholdResults, passed := evaluateThresholds(thresholds, total.view(runDuration, declared))
 
 	return model.TestResult{
-		TestID:        time.Now().Format("20060102150405"),
-		ScriptID:      config.ScriptID,
-		TotalRequests: total,
-		Success:       success,
-		Failure:       failure,
-		AvgLatencyMs:  avgLatency,
-		P90LatencyMs:  p90,
-		P95LatencyMs:  p95,
-		P99LatencyMs:  p99,
-		RPS:           rps,
-		Iterations:    iterations,
-		StartedAt:     startedAt,
+		ScriptID:     config.ScriptID,
+		Config:       config,
+		Summary:      total.summary(runDuration),
+		Scenarios:    results,
+		Thresholds:   thresholdResults,
+		TimeSeries:   sampler.series,
+		DurationSec:  runDuration.Seconds(),
+		Passed:       passed,
+		AbortReason:  abortReason,
+		OutputErrors: outputErrors,
+		StartedAt:    startedAt,
 	}
 }
 
-func percentile(values []int64, p int) int64 {
-	if len(values) == 0 {
-		return 0
+// Scenarios returns the scenarios a config runs. A config without explicit
+// scenarios runs its top-level executor settings as a single default scenario.
+// Scenarios without a script of their own use the config's script.
+func Scenarios(config model.TestConfig) map[string]model.Scenario {
+	if len(config.Scenarios) == 0 {
+		return map[string]model.Scenario{
+			DefaultScenario: {
+				ExecutorConfig: config.ExecutorConfig,
+				ScriptID:       config.ScriptID,
+			},
+		}
+	}
+
+	scenarios := make(map[string]model.Scenario, len(config.Scenarios))
+	for name, scenario := range config.Scenarios {
+		if scenario.ScriptID == "" {
+			scenario.ScriptID = config.ScriptID
+		}
+		scenarios[name] = scenario
 	}
+	return scenarios
+}
+
+// newIteration returns one pass over the script's steps. Response bodies are
+// always read to the end; they are only kept when a check needs them, and
+// never when discardBodies is set. Requests cut short by cancelling ctx are
+// not recorded and the iteration does not count. With tracing, each request
+// carries a traceparent header and is recorded as a span.
+func newIteration(
+	client *http.Client,
+	steps []stepMeta,
+	discardBodies bool,
+	tracing bool,
+) iterationFunc {
+	return func(ctx context.Context, vu *shard, scheduled time.Time) {
+		// An open-model request should have started at its iteration's due
+		// time plus however long the steps before it took.
+		began := time.Now()
+
+		for _, meta := range steps {
+			step := meta.step
+			var resp *http.Response
+			start := time.Now()
+
+			var trace *requestTrace
+			var span spanContext
+			req, err := newRequest(ctx, step)
+			traced := tracing && err == nil
+			if err == nil {
+				if traced {
+					span = newSpanContext()
+					req.Header.Set("traceparent", span.traceparent())
+				}
+				req, trace = traceRequest(req)
+				resp, err = client.Do(req)
+			}
+
+			var body []byte
+			checks := StepChecks(step)
+			if resp != nil {
+				var readErr error
+				if (needsBody(checks) || metricsNeedBody(step.Metrics)) && !discardBodies {
+					body, readErr = io.ReadAll(resp.Body)
+				} else {
+					_, readErr = io.Copy(io.Discard, resp.Body)
+				}
+				resp.Body.Close()
+
+				// A body cut short fails the request, wrapped as client.Do
+				// wraps its own errors so it is classified the same way. Like
+				// a transport error it leaves no response to count or check.
+				if readErr != nil {
+					err = &url.Error{Op: meta.method, URL: meta.url, Err: readErr}
+					resp, body = nil, nil
+				}
+			}
+
+			end := time.Now()
+			duration := end.Sub(start)
+			var phases timings
+			if trace != nil {
+				phases = trace.timings(end)
+				if d := phases.duration(); d > 0 {
+					duration = d
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			failure := classifyFailure(meta, err, resp)
+			responseTime := duration
+			if !scheduled.IsZero() {
+				intended := scheduled.Add(start.Sub(began))
+				responseTime = max(end.Sub(intended), duration)
+			}
+			vu.addRequest(meta, requestSample{
+				duration:     duration,
+				responseTime: responseTime,
+				phases:       phases,
+				status:       status,
+				failure:      failure,
+			})
+			if traced {
+				vu.addSpan(requestSpan(meta, span, start, end, status, failure))
+			}
+
+			outcomes := make([]checkOutcome, len(checks))
+			for i, check := range checks {
+				outcomes[i] = checkOutcome{
+					name:   CheckName(check),
+					passed: evaluateCheck(check, resp, body, duration),
+				}
+			}
+			vu.addChecks(meta, outcomes)
+
+			for _, metric := range meta.metrics {
+				if value, ok := sampleValue(metric.StepMetric, resp, body, duration, outcomes); ok {
+					vu.addMetric(meta, metric, value)
+				}
+			}
+		}
 
-	index := (p * len(values)) / 100
-	if index >= len(values) {
-		index = len(values) - 1
+		vu.addIteration()
 	}
-	return values[index]
 }
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+func runSteps(t *testing.T, config model.TestConfig, steps ...model.Step) model.TestResult {
+	t.Helper()
+	script := &model.Script{ID: "script", Steps: steps}
+	return NewLoadEngine().Run(context.Background(), map[string]*model.Script{"": script}, config, NewBroadcaster(), nil)
+}
+
+func TestDurationIncludesReceiving(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("rest"))
+	}))
+	defer srv.Close()
+
+	var config model.TestConfig
+	config.Executor = model.PerVUIterations
+	config.VUs = 1
+	config.Iterations = 3
+	result := runSteps(t, config, model.Step{
+		Method: "GET",
+		URL:    srv.URL,
+		Checks: []model.Check{{Type: model.CheckMaxDuration, MaxDurationMs: 50}},
+	})
+
+	if result.Latency.Avg < 100 {
+		t.Errorf("http_req_duration avg = %vms, want at least the 100ms spent receiving", result.Latency.Avg)
+	}
+
+	sum := result.Timings["http_req_sending"].Avg + result.Timings["http_req_waiting"].Avg + result.Timings["http_req_receiving"].Avg
+	if math.Abs(result.Latency.Avg-sum) > 0.01 {
+		t.Errorf("http_req_duration avg = %vms, want sending+waiting+receiving = %vms", result.Latency.Avg, sum)
+	}
+
+	if len(result.Checks) != 1 || result.Checks[0].Fails != 3 {
+		t.Errorf("checks = %+v, want the max duration check to fail every request", result.Checks)
+	}
+}
+
+func TestTruncatedBodyFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 1000\r\n\r\nshort")
+		buf.Flush()
+	}))
+	defer srv.Close()
+
+	var config model.TestConfig
+	config.Executor = model.PerVUIterations
+	config.VUs = 1
+	config.Iterations = 3
+	result := runSteps(t, config, model.Step{
+		Method: "GET",
+		URL:    srv.URL,
+		Checks: []model.Check{
+			{Type: model.CheckStatus, StatusCodes: []int{200}},
+			{Type: model.CheckHeader, Header: "Content-Length"},
+		},
+	})
+
+	if result.Success != 0 || result.Failure != 3 {
+		t.Errorf("success = %d, failure = %d, want every truncated response to fail", result.Success, result.Failure)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Category != failureConnectionReset {
+		t.Errorf("errors = %+v, want one %s category", result.Errors, failureConnectionReset)
+	}
+	if result.DataReceived >= 3*1000 {
+		t.Errorf("data received = %d, want only the bytes actually sent", result.DataReceived)
+	}
+	if result.StatusCodes[200] != 0 {
+		t.Errorf("status codes = %v, want no 200 counted for truncated responses", result.StatusCodes)
+	}
+	for _, check := range result.Checks {
+		if check.Passes != 0 || check.Fails != 3 {
+			t.Errorf("check %q = %d passes, %d fails, want every truncated response to fail it", check.Name, check.Passes, check.Fails)
+		}
+	}
+}
+
+func TestResponseTimeIncludesQueueing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// Two VUs serve 10 iterations a second, a fifth of the 50 due.
+	var config model.TestConfig
+	config.Executor = model.ConstantArrivalRate
+	config.Rate = 50
+	config.Duration = 3
+	config.PreAllocatedVUs = 2
+	config.MaxVUs = 2
+	result := runSteps(t, config, model.Step{Method: "GET", URL: srv.URL})
+
+	if result.Latency.P99 > 300 {
+		t.Fatalf("http_req_duration p99 = %vms, want the server's 200ms", result.Latency.P99)
+	}
+	if result.ResponseTime.P99 < result.Latency.P99+150 {
+		t.Errorf("http_req_response_time p99 = %vms, want well above http_req_duration p99 = %vms", result.ResponseTime.P99, result.Latency.P99)
+	}
+	if result.ResponseTime.Avg <= result.Latency.Avg {
+		t.Errorf("http_req_response_time avg = %vms, want above http_req_duration avg = %vms", result.ResponseTime.Avg, result.Latency.Avg)
+	}
+	if result.DroppedIterations == 0 {
+		t.Errorf("dropped iterations = 0, want iterations dropped once the backlog is full")
+	}
+	if got := result.Iterations + result.DroppedIterations; got < 148 || got > 151 {
+		t.Errorf("iterations + dropped = %d, want the 150 due", got)
+	}
+}
+
+func TestScenarioSubMetricsCountIterationsAndData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var config model.TestConfig
+	config.Executor = model.PerVUIterations
+	config.VUs = 1
+	config.Iterations = 5
+	config.Thresholds = []model.Threshold{
+		{Metric: "iterations{scenario:default}", Condition: "count==5"},
+		{Metric: "iterations", Condition: "count==5"},
+		{Metric: "data_sent{scenario:default}", Condition: "count>0"},
+		{Metric: "data_received{scenario:default}", Condition: "count>0"},
+		{Metric: "dropped_iterations{scenario:default}", Condition: "count==0"},
+		{Metric: "iterations{scenario:other}", Condition: "count==0"},
+	}
+	result := runSteps(t, config, model.Step{Method: "GET", URL: srv.URL})
+
+	for _, threshold := range result.Thresholds {
+		if !threshold.Passed {
+			t.Errorf("threshold %s %s failed with %v", threshold.Metric, threshold.Condition, threshold.Actual)
+		}
+	}
+	if !result.Passed {
+		t.Error("run failed its thresholds")
+	}
+}
+
+func TestMaxDurationCancelsHungRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	for _, executor := range []model.Executor{model.PerVUIterations, model.SharedIterations} {
+		var config model.TestConfig
+		config.Executor = executor
+		config.VUs = 2
+		config.Iterations = 2
+		config.MaxDuration = 1
+
+		began := time.Now()
+		result := runSteps(t, config, model.Step{Method: "GET", URL: srv.URL})
+		if elapsed := time.Since(began); elapsed > 3*time.Second {
+			t.Errorf("%s: run took %v, want it cut off at the 1s maxDuration", executor, elapsed)
+		}
+		if result.Iterations != 0 || result.TotalRequests != 0 {
+			t.Errorf("%s: iterations = %d, requests = %d, want the interrupted ones not counted", executor, result.Iterations, result.TotalRequests)
+		}
+	}
+}
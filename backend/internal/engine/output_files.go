@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+// csvTags are the tags that get a column of their own in CSV output, in
+// column order. Any other tag goes to extra_tags.
+var csvTags = []string{"check", "error", "method", "name", "scenario", "status", "url"}
+
+// ValidateOutputFile checks that name is a bare file name, so a file output
+// cannot write outside the outputs directory.
+func ValidateOutputFile(name string) error {
+	switch {
+	case name == "":
+		return errors.New("path is required")
+	case name == "." || name == "..", strings.ContainsAny(name, `/\`), filepath.IsAbs(name):
+		return errors.New("path must be a file name: " + name)
+	}
+	return nil
+}
+
+// createOutputFile creates the file name in dir. It fails if the file exists,
+// and os.Root keeps symlinks from leading out of dir.
+func createOutputFile(dir, name string) (*os.File, error) {
+	if err := ValidateOutputFile(name); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+}
+
+// jsonOutput writes newline-delimited JSON in the layout of k6's JSON output:
+// a "Metric" line the first time a metric is seen, then a "Point" line per
+// sample. Snapshots are written as "Snapshot" lines.
+type jsonOutput struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	seen    map[string]bool
+}
+
+type jsonLine struct {
+	Type   string `json:"type"`
+	Metric string `json:"metric,omitempty"`
+	Data   any    `json:"data"`
+}
+
+type jsonMetric struct {
+	Name string           `json:"name"`
+	Type model.MetricType `json:"type"`
+}
+
+type jsonPoint struct {
+	Time  time.Time         `json:"time"`
+	Value float64           `json:"value"`
+	Tags  map[string]string `json:"tags"`
+}
+
+func newJSONOutput(dir, name string) (*jsonOutput, error) {
+	file, err := createOutputFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &jsonOutput{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+		seen:    make(map[string]bool),
+	}, nil
+}
+
+func (o *jsonOutput) AddSamples(samples []Sample) error {
+	for _, sample := range samples {
+		if !o.seen[sample.Metric] {
+			o.seen[sample.Metric] = true
+			metric := jsonMetric{Name: sample.Metric, Type: sample.Type}
+			if err := o.encoder.Encode(jsonLine{Type: "Metric", Metric: sample.Metric, Data: metric}); err != nil {
+				return err
+			}
+		}
+
+		point := jsonPoint{Time: sample.Time, Value: sample.Value, Tags: sample.Tags}
+		if err := o.encoder.Encode(jsonLine{Type: "Point", Metric: sample.Metric, Data: point}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *jsonOutput) AddSnapshot(snapshot model.Snapshot) error {
+	if err := o.encoder.Encode(jsonLine{Type: "Snapshot", Data: snapshot}); err != nil {
+		return err
+	}
+	return o.writer.Flush()
+}
+
+func (o *jsonOutput) Close() error {
+	return errors.Join(o.writer.Flush(), o.file.Close())
+}
+
+// csvOutput writes one row per sample with the columns of k6's CSV output:
+// the metric, a unix timestamp in seconds, the value, the common tags and the
+// remaining tags as extra_tags ("key=value&key=value").
+type csvOutput struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newCSVOutput(dir, name string) (*csvOutput, error) {
+	file, err := createOutputFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := csv.NewWriter(file)
+	header := append([]string{"metric_name", "timestamp", "metric_value"}, csvTags...)
+	if err := writer.Write(append(header, "extra_tags")); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvOutput{file: file, writer: writer}, nil
+}
+
+func (o *csvOutput) AddSamples(samples []Sample) error {
+	for _, sample := range samples {
+		row := []string{
+			sample.Metric,
+			strconv.FormatInt(sample.Time.Unix(), 10),
+			strconv.FormatFloat(sample.Value, 'f', -1, 64),
+		}
+		for _, tag := range csvTags {
+			row = append(row, sample.Tags[tag])
+		}
+
+		var extra []string
+		for _, key := range slices.Sorted(maps.Keys(sample.Tags)) {
+			if !slices.Contains(csvTags, key) {
+				extra = append(extra, key+"="+sample.Tags[key])
+			}
+		}
+		if err := o.writer.Write(append(row, strings.Join(extra, "&"))); err != nil {
+			return err
+		}
+	}
+
+	o.writer.Flush()
+	return o.writer.Error()
+}
+
+// AddSnapshot writes nothing: the run-wide values of a snapshot already
+// arrive as vus, dropped_iterations and data samples.
+func (o *csvOutput) AddSnapshot(model.Snapshot) error {
+	return nil
+}
+
+func (o *csvOutput) Close() error {
+	o.writer.Flush()
+	return errors.Join(o.writer.Error(), o.file.Close())
+}
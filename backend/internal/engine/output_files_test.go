@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateOutputFileRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"", ".", "..", "../escape.json", "/tmp/escape.json", "sub/file.json", `sub\file.json`} {
+		if file, err := createOutputFile(dir, name); err == nil {
+			file.Close()
+			t.Errorf("createOutputFile(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestCreateOutputFileDoesNotOverwrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outputs")
+
+	file, err := createOutputFile(dir, "run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if file, err := createOutputFile(dir, "run.json"); err == nil {
+		file.Close()
+		t.Error("createOutputFile over an existing file succeeded, want an error")
+	}
+}
+
+func TestCreateOutputFileDoesNotFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "target")
+	if err := os.WriteFile(target, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(dir, "link.json")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	if file, err := createOutputFile(dir, "link.json"); err == nil {
+		file.Close()
+		t.Error("createOutputFile through a symlink succeeded, want an error")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Errorf("symlink target = %q, want it untouched", data)
+	}
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	// datagramSize keeps UDP packets within a typical MTU.
+	datagramSize        = 1432
+	influxTimeout       = 10 * time.Second
+	defaultStatsDPrefix = "k6."
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	statsDNameEscaper        = strings.NewReplacer(":", "_", "|", "_", "\n", "_")
+)
+
+// influxOutput sends samples in InfluxDB line protocol, one point per sample
+// with the value in a "value" field. An http(s) URL is the write endpoint
+// (such as http://localhost:8086/write?db=k6); a udp://host:port URL sends the
+// lines as datagrams.
+type influxOutput struct {
+	endpoint string
+	client   *http.Client
+	conn     net.Conn
+}
+
+func newInfluxOutput(rawURL string) (*influxOutput, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, errors.New("invalid url: " + rawURL)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &influxOutput{endpoint: rawURL, client: &http.Client{Timeout: influxTimeout}}, nil
+	case "udp":
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &influxOutput{conn: conn}, nil
+	}
+	return nil, errors.New("unsupported url scheme: " + u.Scheme)
+}
+
+func (o *influxOutput) AddSamples(samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(samples))
+	for i, sample := range samples {
+		lines[i] = influxLine(sample)
+	}
+
+	if o.conn != nil {
+		return writeDatagrams(o.conn, lines)
+	}
+
+	resp, err := o.client.Post(o.endpoint, "text/plain; charset=utf-8",
+		strings.NewReader(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("influxdb write failed: " + resp.Status + ": " + strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (o *influxOutput) AddSnapshot(model.Snapshot) error {
+	return nil
+}
+
+func (o *influxOutput) Close() error {
+	if o.conn != nil {
+		return o.conn.Close()
+	}
+	o.client.CloseIdleConnections()
+	return nil
+}
+
+func influxLine(sample Sample) string {
+	var b strings.Builder
+	b.WriteString(influxMeasurementEscaper.Replace(sample.Metric))
+	for _, key := range slices.Sorted(maps.Keys(sample.Tags)) {
+		// Line protocol has no empty tag values.
+		if value := sample.Tags[key]; value != "" {
+			b.WriteString("," + influxTagEscaper.Replace(key) + "=" + influxTagEscaper.Replace(value))
+		}
+	}
+	b.WriteString(" value=" + strconv.FormatFloat(sample.Value, 'f', -1, 64))
+	b.WriteString(" " + strconv.FormatInt(sample.Time.UnixNano(), 10))
+	return b.String()
+}
+
+// statsDOutput sends samples to a StatsD server: counters as "c", gauges as
+// "g", trends as "ms" timings and rates as a count of the non-zero samples.
+// Tags are not sent.
+type statsDOutput struct {
+	conn   net.Conn
+	prefix string
+}
+
+func newStatsDOutput(address, prefix string) (*statsDOutput, error) {
+	if address == "" {
+		return nil, errors.New("address is required")
+	}
+	if prefix == "" {
+		prefix = defaultStatsDPrefix
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &statsDOutput{conn: conn, prefix: prefix}, nil
+}
+
+func (o *statsDOutput) AddSamples(samples []Sample) error {
+	lines := make([]string, 0, len(samples))
+	for _, sample := range samples {
+		name := statsDNameEscaper.Replace(o.prefix + sample.Metric)
+		value := strconv.FormatFloat(sample.Value, 'f', -1, 64)
+
+		switch sample.Type {
+		case model.MetricCounter:
+			lines = append(lines, name+":"+value+"|c")
+		case model.MetricGauge:
+			lines = append(lines, name+":"+value+"|g")
+		case model.MetricTrend:
+			lines = append(lines, name+":"+value+"|ms")
+		case model.MetricRate:
+			if sample.Value != 0 {
+				lines = append(lines, name+":1|c")
+			}
+		}
+	}
+	return writeDatagrams(o.conn, lines)
+}
+
+func (o *statsDOutput) AddSnapshot(model.Snapshot) error {
+	return nil
+}
+
+func (o *statsDOutput) Close() error {
+	return o.conn.Close()
+}
+
+// writeDatagrams sends newline-separated lines, packing as many as fit in a
+// datagram. A line longer than a datagram is sent on its own.
+func writeDatagrams(conn net.Conn, lines []string) error {
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > datagramSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	return flush()
+}
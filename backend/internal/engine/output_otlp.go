@@ -0,0 +1,420 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	otlpServiceName = "k6"
+	otlpTimeout     = 10 * time.Second
+
+	otlpTemporalityDelta = 1
+	otlpSpanKindClient   = 3
+	otlpStatusError      = 2
+)
+
+// otlpBounds are the histogram bucket bounds, in milliseconds, of exported
+// trends.
+var otlpBounds = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// otlpOutput exports to an OpenTelemetry collector over OTLP/HTTP with JSON
+// encoding. Samples are aggregated per snapshot interval: counters become
+// delta sums, gauges keep their last value, trends become delta histograms
+// and rates the fraction of non-zero samples. With traces, the spans of
+// traced requests are exported as they are drained.
+type otlpOutput struct {
+	endpoint string
+	headers  map[string]string
+	traces   bool
+	client   *http.Client
+	start    time.Time
+	series   map[customKey]*otlpSeries
+}
+
+type otlpSeries struct {
+	name    string
+	kind    model.MetricType
+	tags    map[string]string
+	count   int64
+	nonZero int64
+	sum     float64
+	min     float64
+	max     float64
+	last    float64
+	buckets []int64
+}
+
+func newOTLPOutput(rawURL string, headers map[string]string, traces bool) (*otlpOutput, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("invalid url: " + rawURL)
+	}
+
+	return &otlpOutput{
+		endpoint: strings.TrimRight(rawURL, "/"),
+		headers:  headers,
+		traces:   traces,
+		client:   &http.Client{Timeout: otlpTimeout},
+		start:    time.Now(),
+		series:   make(map[customKey]*otlpSeries),
+	}, nil
+}
+
+func (o *otlpOutput) AddSamples(samples []Sample) error {
+	for _, sample := range samples {
+		key := newCustomKey(sample.Metric, sample.Tags)
+		series, ok := o.series[key]
+		if !ok {
+			series = &otlpSeries{name: sample.Metric, kind: sample.Type, tags: sample.Tags}
+			if sample.Type == model.MetricTrend {
+				series.buckets = make([]int64, len(otlpBounds)+1)
+			}
+			o.series[key] = series
+		}
+		series.add(sample.Value)
+	}
+	return nil
+}
+
+func (s *otlpSeries) add(value float64) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.sum += value
+	s.last = value
+	if value != 0 {
+		s.nonZero++
+	}
+	if s.buckets != nil {
+		// Bucket i counts values in (bounds[i-1], bounds[i]].
+		i, _ := slices.BinarySearch(otlpBounds, value)
+		s.buckets[i]++
+	}
+}
+
+// AddSnapshot exports the metrics aggregated since the previous snapshot.
+func (o *otlpOutput) AddSnapshot(snapshot model.Snapshot) error {
+	start, end := o.start, snapshot.Time
+	o.start = end
+	if len(o.series) == 0 {
+		return nil
+	}
+
+	byName := make(map[string][]*otlpSeries)
+	for _, series := range o.series {
+		byName[series.name] = append(byName[series.name], series)
+	}
+	clear(o.series)
+
+	var metrics []otlpMetric
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		metrics = append(metrics, otlpMetricOf(name, byName[name], start, end))
+	}
+
+	return o.post("/v1/metrics", otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource:     otlpResource(),
+			ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: otlpServiceName}, Metrics: metrics}},
+		}},
+	})
+}
+
+func (o *otlpOutput) AddSpans(spans []Span) error {
+	if !o.traces {
+		return nil
+	}
+
+	exported := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		exported[i] = otlpSpanOf(span)
+	}
+
+	return o.post("/v1/traces", otlpTracesRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource(),
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otlpServiceName}, Spans: exported}},
+		}},
+	})
+}
+
+func (o *otlpOutput) Close() error {
+	o.client.CloseIdleConnections()
+	return nil
+}
+
+func (o *otlpOutput) post(path string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range o.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("otlp export to " + path + " failed: " + resp.Status + ": " + strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func otlpMetricOf(name string, series []*otlpSeries, start, end time.Time) otlpMetric {
+	slices.SortFunc(series, func(a, b *otlpSeries) int {
+		return strings.Compare(tagKey(a.tags), tagKey(b.tags))
+	})
+
+	metric := otlpMetric{Name: name}
+	switch {
+	case name == "data_sent" || name == "data_received":
+		metric.Unit = "By"
+	case series[0].kind == model.MetricTrend && builtinMetric(name):
+		metric.Unit = "ms"
+	}
+
+	point := func(s *otlpSeries) otlpNumberPoint {
+		return otlpNumberPoint{
+			Attributes:        otlpAttributes(s.tags),
+			StartTimeUnixNano: otlpTime(start),
+			TimeUnixNano:      otlpTime(end),
+		}
+	}
+
+	switch series[0].kind {
+	case model.MetricCounter:
+		sum := &otlpSum{AggregationTemporality: otlpTemporalityDelta, IsMonotonic: true}
+		for _, s := range series {
+			p := point(s)
+			p.AsDouble = s.sum
+			sum.DataPoints = append(sum.DataPoints, p)
+		}
+		metric.Sum = sum
+	case model.MetricGauge, model.MetricRate:
+		gauge := &otlpGauge{}
+		for _, s := range series {
+			p := point(s)
+			p.AsDouble = s.last
+			if s.kind == model.MetricRate {
+				p.AsDouble = float64(s.nonZero) / float64(s.count)
+			}
+			gauge.DataPoints = append(gauge.DataPoints, p)
+		}
+		metric.Gauge = gauge
+	case model.MetricTrend:
+		histogram := &otlpHistogram{AggregationTemporality: otlpTemporalityDelta}
+		for _, s := range series {
+			counts := make([]string, len(s.buckets))
+			for i, n := range s.buckets {
+				counts[i] = strconv.FormatInt(n, 10)
+			}
+			histogram.DataPoints = append(histogram.DataPoints, otlpHistogramPoint{
+				Attributes:        otlpAttributes(s.tags),
+				StartTimeUnixNano: otlpTime(start),
+				TimeUnixNano:      otlpTime(end),
+				Count:             strconv.FormatInt(s.count, 10),
+				Sum:               s.sum,
+				Min:               s.min,
+				Max:               s.max,
+				BucketCounts:      counts,
+				ExplicitBounds:    otlpBounds,
+			})
+		}
+		metric.Histogram = histogram
+	}
+	return metric
+}
+
+// otlpSpanOf maps a request span onto the HTTP client semantic conventions.
+// Tags other than method and url are kept under the k6 namespace.
+func otlpSpanOf(span Span) otlpSpan {
+	attributes := []otlpAttribute{
+		otlpString("http.request.method", span.Tags["method"]),
+		otlpString("url.full", span.Tags["url"]),
+	}
+	if span.Status > 0 {
+		attributes = append(attributes, otlpAttribute{
+			Key:   "http.response.status_code",
+			Value: otlpValue{IntValue: strconv.Itoa(span.Status)},
+		})
+	}
+	if span.Error != "" {
+		attributes = append(attributes, otlpString("error.type", span.Error))
+	}
+	for _, key := range slices.Sorted(maps.Keys(span.Tags)) {
+		if key != "method" && key != "url" {
+			attributes = append(attributes, otlpString("k6."+key, span.Tags[key]))
+		}
+	}
+
+	exported := otlpSpan{
+		TraceID:           hex.EncodeToString(span.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.SpanID[:]),
+		Name:              span.Name,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: otlpTime(span.Start),
+		EndTimeUnixNano:   otlpTime(span.End),
+		Attributes:        attributes,
+	}
+	if span.Error != "" {
+		exported.Status = &otlpStatus{Code: otlpStatusError, Message: span.Error}
+	}
+	return exported
+}
+
+func otlpResource() otlpResourceAttributes {
+	return otlpResourceAttributes{Attributes: []otlpAttribute{otlpString("service.name", otlpServiceName)}}
+}
+
+func otlpAttributes(tags map[string]string) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		attributes = append(attributes, otlpString(key, tags[key]))
+	}
+	return attributes
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+// otlpTime renders a timestamp the way the OTLP JSON encoding expects 64-bit
+// integers: as a decimal string.
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// The types below follow the OTLP/HTTP JSON encoding of the metrics and
+// trace export requests, limited to the fields the engine sends.
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+type otlpResourceAttributes struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResourceAttributes `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics     `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit,omitempty"`
+	Sum       *otlpSum       `json:"sum,omitempty"`
+	Gauge     *otlpGauge     `json:"gauge,omitempty"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberPoint `json:"dataPoints"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpHistogramPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	Min               float64         `json:"min"`
+	Max               float64         `json:"max"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResourceAttributes `json:"resource"`
+	ScopeSpans []otlpScopeSpans       `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,405 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+// otlpRequest is one export request as a collector receives it.
+type otlpRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// otlpReceiver records the export requests of an OTLP output. It answers
+// every request with status.
+type otlpReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []otlpRequest
+}
+
+func newOTLPReceiver(t *testing.T, status int) *otlpReceiver {
+	t.Helper()
+	receiver := &otlpReceiver{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, otlpRequest{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		receiver.mu.Unlock()
+		if status != http.StatusOK {
+			http.Error(w, "rejected", status)
+		}
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *otlpReceiver) received(path string) []otlpRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var requests []otlpRequest
+	for _, request := range r.requests {
+		if request.path == path {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// The types below decode the OTLP JSON encoding independently of the ones
+// the output marshals, so a renamed field shows up as a missing value.
+
+type otlpTestAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+		IntValue    string `json:"intValue"`
+	} `json:"value"`
+}
+
+type otlpTestPoint struct {
+	Attributes        []otlpTestAttribute `json:"attributes"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	TimeUnixNano      string              `json:"timeUnixNano"`
+	AsDouble          float64             `json:"asDouble"`
+	Count             string              `json:"count"`
+	Sum               float64             `json:"sum"`
+	Min               float64             `json:"min"`
+	Max               float64             `json:"max"`
+	BucketCounts      []string            `json:"bucketCounts"`
+	ExplicitBounds    []float64           `json:"explicitBounds"`
+}
+
+type otlpTestData struct {
+	DataPoints             []otlpTestPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpTestMetric struct {
+	Name      string        `json:"name"`
+	Unit      string        `json:"unit"`
+	Sum       *otlpTestData `json:"sum"`
+	Gauge     *otlpTestData `json:"gauge"`
+	Histogram *otlpTestData `json:"histogram"`
+}
+
+type otlpTestSpan struct {
+	TraceID           string              `json:"traceId"`
+	SpanID            string              `json:"spanId"`
+	Name              string              `json:"name"`
+	Kind              int                 `json:"kind"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string              `json:"endTimeUnixNano"`
+	Attributes        []otlpTestAttribute `json:"attributes"`
+	Status            *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type otlpTestResource struct {
+	Attributes []otlpTestAttribute `json:"attributes"`
+}
+
+func decodeOTLPMetrics(t *testing.T, body []byte) []otlpTestMetric {
+	t.Helper()
+	var request struct {
+		ResourceMetrics []struct {
+			Resource     otlpTestResource `json:"resource"`
+			ScopeMetrics []struct {
+				Metrics []otlpTestMetric `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.ResourceMetrics) != 1 || len(request.ResourceMetrics[0].ScopeMetrics) != 1 {
+		t.Fatalf("metrics request = %s, want one resource and scope", body)
+	}
+	checkServiceName(t, request.ResourceMetrics[0].Resource)
+	return request.ResourceMetrics[0].ScopeMetrics[0].Metrics
+}
+
+func decodeOTLPSpans(t *testing.T, body []byte) []otlpTestSpan {
+	t.Helper()
+	var request struct {
+		ResourceSpans []struct {
+			Resource   otlpTestResource `json:"resource"`
+			ScopeSpans []struct {
+				Spans []otlpTestSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.ResourceSpans) != 1 || len(request.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("traces request = %s, want one resource and scope", body)
+	}
+	checkServiceName(t, request.ResourceSpans[0].Resource)
+	return request.ResourceSpans[0].ScopeSpans[0].Spans
+}
+
+func checkServiceName(t *testing.T, resource otlpTestResource) {
+	t.Helper()
+	if got := attributeMap(resource.Attributes)["service.name"]; got != "k6" {
+		t.Errorf("service.name = %q, want k6", got)
+	}
+}
+
+// attributeMap flattens attributes, rendering int values as their decimal
+// string.
+func attributeMap(attributes []otlpTestAttribute) map[string]string {
+	m := make(map[string]string, len(attributes))
+	for _, attribute := range attributes {
+		m[attribute.Key] = attribute.Value.StringValue + attribute.Value.IntValue
+	}
+	return m
+}
+
+func TestOTLPMetrics(t *testing.T) {
+	receiver := newOTLPReceiver(t, http.StatusOK)
+	output := openOutput(t, "", model.OutputConfig{
+		Type:    model.OutputOTLP,
+		URL:     receiver.URL + "/",
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Scope-OrgID": "team"},
+	})
+	writeSamples(t, output, testSamples())
+
+	requests := receiver.received("/v1/metrics")
+	if len(requests) != 1 {
+		t.Fatalf("metrics requests = %d, want 1", len(requests))
+	}
+	header := requests[0].header
+	for key, want := range map[string]string{"Content-Type": "application/json", "Authorization": "Bearer secret", "X-Scope-OrgID": "team"} {
+		if got := header.Get(key); got != want {
+			t.Errorf("header %s = %q, want %q", key, got, want)
+		}
+	}
+
+	metrics := decodeOTLPMetrics(t, requests[0].body)
+	names := make([]string, len(metrics))
+	byName := make(map[string]otlpTestMetric)
+	for i, metric := range metrics {
+		names[i] = metric.Name
+		byName[metric.Name] = metric
+	}
+	if want := []string{"checks", "http_req_duration", "http_req_failed", "http_reqs", "vus"}; !slices.Equal(names, want) {
+		t.Fatalf("metrics = %v, want %v", names, want)
+	}
+
+	end := otlpTime(sampleTime)
+	onePoint := func(name string, data *otlpTestData) otlpTestPoint {
+		t.Helper()
+		if data == nil || len(data.DataPoints) != 1 {
+			t.Fatalf("%s = %+v, want one data point", name, data)
+		}
+		point := data.DataPoints[0]
+		if point.TimeUnixNano != end || point.StartTimeUnixNano == "" {
+			t.Errorf("%s times = %s..%s, want ending at %s", name, point.StartTimeUnixNano, point.TimeUnixNano, end)
+		}
+		return point
+	}
+
+	reqs := byName["http_reqs"]
+	point := onePoint("http_reqs", reqs.Sum)
+	if point.AsDouble != 2 || reqs.Sum.AggregationTemporality != 1 || !reqs.Sum.IsMonotonic {
+		t.Errorf("http_reqs = %+v, want a monotonic delta sum of 2", reqs.Sum)
+	}
+	tags := attributeMap(point.Attributes)
+	if tags["name"] != "login page" || tags["url"] != "http://example.test/a,b" || tags["team"] != "x=1" {
+		t.Errorf("http_reqs attributes = %v, want the sample tags", tags)
+	}
+
+	duration := byName["http_req_duration"]
+	if duration.Unit != "ms" {
+		t.Errorf("http_req_duration unit = %q, want ms", duration.Unit)
+	}
+	point = onePoint("http_req_duration", duration.Histogram)
+	if duration.Histogram.AggregationTemporality != 1 {
+		t.Errorf("http_req_duration temporality = %d, want delta", duration.Histogram.AggregationTemporality)
+	}
+	if point.Count != "1" || point.Sum != 12.5 || point.Min != 12.5 || point.Max != 12.5 {
+		t.Errorf("http_req_duration point = %+v, want one 12.5ms value", point)
+	}
+	if !slices.Equal(point.ExplicitBounds, otlpBounds) {
+		t.Errorf("explicit bounds = %v, want %v", point.ExplicitBounds, otlpBounds)
+	}
+	// 12.5ms falls in (10, 25], the fifth bucket.
+	wantCounts := make([]string, len(otlpBounds)+1)
+	for i := range wantCounts {
+		wantCounts[i] = "0"
+	}
+	wantCounts[4] = "1"
+	if !slices.Equal(point.BucketCounts, wantCounts) {
+		t.Errorf("bucket counts = %v, want %v", point.BucketCounts, wantCounts)
+	}
+
+	for name, want := range map[string]float64{"http_req_failed": 0, "checks": 1, "vus": 3} {
+		metric := byName[name]
+		if metric.Sum != nil || metric.Histogram != nil {
+			t.Errorf("%s = %+v, want a gauge", name, metric)
+			continue
+		}
+		if point := onePoint(name, metric.Gauge); point.AsDouble != want {
+			t.Errorf("%s = %v, want %v", name, point.AsDouble, want)
+		}
+	}
+}
+
+func TestOTLPMetricsExportError(t *testing.T) {
+	receiver := newOTLPReceiver(t, http.StatusBadRequest)
+	output := openOutput(t, "", model.OutputConfig{Type: model.OutputOTLP, URL: receiver.URL})
+	defer output.Close()
+
+	if err := output.AddSamples(testSamples()); err != nil {
+		t.Fatal(err)
+	}
+	err := output.AddSnapshot(model.Snapshot{Time: sampleTime})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("AddSnapshot error = %v, want the collector's 400 and message", err)
+	}
+}
+
+func TestOTLPTraces(t *testing.T) {
+	receiver := newOTLPReceiver(t, http.StatusOK)
+	output := openOutput(t, "", model.OutputConfig{Type: model.OutputOTLP, URL: receiver.URL, Traces: true})
+	defer output.Close()
+
+	span := Span{
+		TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Name:    "GET login page",
+		Start:   sampleTime,
+		End:     sampleTime.Add(12500 * time.Microsecond),
+		Status:  503,
+		Error:   "http_5xx",
+		Tags:    map[string]string{"method": "GET", "url": "http://example.test/login", "name": "login page", "scenario": "default"},
+	}
+	if err := output.(SpanOutput).AddSpans([]Span{span}); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := receiver.received("/v1/traces")
+	if len(requests) != 1 {
+		t.Fatalf("traces requests = %d, want 1", len(requests))
+	}
+	spans := decodeOTLPSpans(t, requests[0].body)
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	got := spans[0]
+
+	if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || got.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("span ids = %s/%s, want the hex trace and span id", got.TraceID, got.SpanID)
+	}
+	if got.Name != span.Name || got.Kind != 3 {
+		t.Errorf("span = %q kind %d, want %q kind client", got.Name, got.Kind, span.Name)
+	}
+	if got.StartTimeUnixNano != otlpTime(span.Start) || got.EndTimeUnixNano != otlpTime(span.End) {
+		t.Errorf("span times = %s..%s, want %s..%s", got.StartTimeUnixNano, got.EndTimeUnixNano, otlpTime(span.Start), otlpTime(span.End))
+	}
+	if got.Status == nil || got.Status.Code != 2 || got.Status.Message != "http_5xx" {
+		t.Errorf("span status = %+v, want an error with the failure category", got.Status)
+	}
+
+	want := map[string]string{
+		"http.request.method":       "GET",
+		"url.full":                  "http://example.test/login",
+		"http.response.status_code": "503",
+		"error.type":                "http_5xx",
+		"k6.name":                   "login page",
+		"k6.scenario":               "default",
+	}
+	attributes := attributeMap(got.Attributes)
+	if len(attributes) != len(want) {
+		t.Errorf("span attributes = %v, want %v", attributes, want)
+	}
+	for key, value := range want {
+		if attributes[key] != value {
+			t.Errorf("span attribute %s = %q, want %q", key, attributes[key], value)
+		}
+	}
+}
+
+func TestOTLPTracesDisabled(t *testing.T) {
+	receiver := newOTLPReceiver(t, http.StatusOK)
+	output := openOutput(t, "", model.OutputConfig{Type: model.OutputOTLP, URL: receiver.URL})
+	defer output.Close()
+
+	if err := output.(SpanOutput).AddSpans([]Span{{Name: "GET home"}}); err != nil {
+		t.Fatal(err)
+	}
+	if requests := receiver.received("/v1/traces"); len(requests) != 0 {
+		t.Errorf("traces requests = %d, want none without traces", len(requests))
+	}
+}
+
+func TestRunPropagatesTraceparent(t *testing.T) {
+	var mu sync.Mutex
+	var traceparents []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	receiver := newOTLPReceiver(t, http.StatusOK)
+	var config model.TestConfig
+	config.Outputs = []model.OutputConfig{{Type: model.OutputOTLP, URL: receiver.URL, Traces: true}}
+	outputs, err := NewOutputs(t.TempDir(), config.Outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Executor = model.PerVUIterations
+	config.VUs = 1
+	config.Iterations = 3
+	script := &model.Script{ID: "script", Steps: []model.Step{{Method: "GET", URL: target.URL, Name: "home"}}}
+	result := NewLoadEngine().Run(context.Background(), map[string]*model.Script{"": script}, config, NewBroadcaster(), outputs)
+	if len(result.OutputErrors) > 0 {
+		t.Fatal(result.OutputErrors)
+	}
+
+	var exported []string
+	for _, request := range receiver.received("/v1/traces") {
+		for _, span := range decodeOTLPSpans(t, request.body) {
+			if span.Name != "GET home" || span.Kind != 3 {
+				t.Errorf("span = %q kind %d, want GET home kind client", span.Name, span.Kind)
+			}
+			exported = append(exported, "00-"+span.TraceID+"-"+span.SpanID+"-01")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(traceparents) != 3 {
+		t.Fatalf("requests = %d, want 3", len(traceparents))
+	}
+	for _, traceparent := range traceparents {
+		if len(traceparent) != len("00-")+32+1+16+len("-01") {
+			t.Errorf("traceparent = %q, want a W3C trace context", traceparent)
+		}
+	}
+	if !slices.Equal(slices.Sorted(slices.Values(traceparents)), slices.Sorted(slices.Values(exported))) {
+		t.Errorf("traceparent headers = %v, want one per exported span %v", traceparents, exported)
+	}
+	if len(receiver.received("/v1/metrics")) == 0 {
+		t.Error("no metrics exported alongside the traces")
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"errors"
+	"maps"
+	"strconv"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const outputBacklog = 64
+
+// Sample is one raw metric value as a run records it. Trend values are in
+// milliseconds.
+type Sample struct {
+	Metric string
+	Type   model.MetricType
+	Time   time.Time
+	Value  float64
+	Tags   map[string]string
+}
+
+// Output receives a run's raw samples and its per-second snapshots. Calls
+// come from a single goroutine; Close is called once the run has ended.
+type Output interface {
+	AddSamples(samples []Sample) error
+	AddSnapshot(snapshot model.Snapshot) error
+	Close() error
+}
+
+// NewOutputs opens the sinks a run writes to. File outputs are created in dir.
+func NewOutputs(dir string, configs []model.OutputConfig) ([]Output, error) {
+	outputs := make([]Output, 0, len(configs))
+	for _, config := range configs {
+		output, err := newOutput(dir, config)
+		if err != nil {
+			for _, opened := range outputs {
+				opened.Close()
+			}
+			return nil, errors.New("output " + string(config.Type) + ": " + err.Error())
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func newOutput(dir string, config model.OutputConfig) (Output, error) {
+	switch config.Type {
+	case model.OutputJSON:
+		return newJSONOutput(dir, config.Path)
+	case model.OutputCSV:
+		return newCSVOutput(dir, config.Path)
+	case model.OutputInflux:
+		return newInfluxOutput(config.URL)
+	case model.OutputStatsD:
+		return newStatsDOutput(config.Address, config.Prefix)
+	case model.OutputOTLP:
+		return newOTLPOutput(config.URL, config.Headers, config.Traces)
+	}
+	return nil, errors.New("unknown output type")
+}
+
+type outputBatch struct {
+	samples  []Sample
+	spans    []Span
+	snapshot model.Snapshot
+}
+
+// outputWriter feeds each output from a goroutine of its own, so a slow sink
+// never delays sampling or the other sinks. An output whose backlog fills up
+// is stopped rather than waited for; like a failing one, it keeps its first
+// error.
+type outputWriter struct {
+	sinks []*outputSink
+}
+
+type outputSink struct {
+	output  Output
+	batches chan outputBatch
+	err     error
+	behind  bool
+	done    chan struct{}
+}
+
+func newOutputWriter(outputs []Output) *outputWriter {
+	w := &outputWriter{sinks: make([]*outputSink, len(outputs))}
+	for i, output := range outputs {
+		sink := &outputSink{
+			output:  output,
+			batches: make(chan outputBatch, outputBacklog),
+			done:    make(chan struct{}),
+		}
+		go sink.run()
+		w.sinks[i] = sink
+	}
+	return w
+}
+
+func (s *outputSink) run() {
+	defer close(s.done)
+	for batch := range s.batches {
+		if s.err != nil {
+			continue
+		}
+		if s.err = s.output.AddSamples(batch.samples); s.err != nil {
+			continue
+		}
+		if spans, ok := s.output.(SpanOutput); ok && len(batch.spans) > 0 {
+			if s.err = spans.AddSpans(batch.spans); s.err != nil {
+				continue
+			}
+		}
+		s.err = s.output.AddSnapshot(batch.snapshot)
+	}
+}
+
+func (w *outputWriter) write(batch outputBatch) {
+	for _, sink := range w.sinks {
+		if sink.behind {
+			continue
+		}
+		select {
+		case sink.batches <- batch:
+		default:
+			sink.behind = true
+			close(sink.batches)
+		}
+	}
+}
+
+// close flushes the pending batches, closes the outputs and returns their
+// errors.
+func (w *outputWriter) close() []string {
+	for _, sink := range w.sinks {
+		if !sink.behind {
+			close(sink.batches)
+		}
+	}
+
+	var errs []string
+	for _, sink := range w.sinks {
+		<-sink.done
+		err := sink.err
+		if err == nil && sink.behind {
+			err = errors.New("fell " + strconv.Itoa(outputBacklog) + " snapshots behind; stopped writing to it")
+		}
+		if closeErr := sink.output.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// requestSamples keeps the samples of one request for the outputs, tagged
+// like its sub-metrics plus the status and error category.
+func (s *shard) requestSamples(step stepMeta, sample requestSample, now time.Time) {
+	tags := maps.Clone(step.tags)
+	tags["status"] = strconv.Itoa(sample.status)
+	failed := 0.0
+	if sample.failure != nil {
+		tags["error"] = sample.failure.category
+		failed = 1
+	}
+
+	s.samples = append(s.samples,
+		Sample{Metric: "http_reqs", Type: model.MetricCounter, Time: now, Value: 1, Tags: tags},
+		Sample{Metric: "http_req_duration", Type: model.MetricTrend, Time: now, Value: durationMillis(sample.duration), Tags: tags},
+		Sample{Metric: "http_req_response_time", Type: model.MetricTrend, Time: now, Value: durationMillis(sample.responseTime), Tags: tags},
+		Sample{Metric: "http_req_failed", Type: model.MetricRate, Time: now, Value: failed, Tags: tags},
+	)
+	for i, phase := range sample.phases {
+		s.samples = append(s.samples,
+			Sample{Metric: timingMetrics[i], Type: model.MetricTrend, Time: now, Value: durationMillis(phase), Tags: tags})
+	}
+}
+
+func (s *shard) checkSample(step stepMeta, outcome checkOutcome, now time.Time) {
+	tags := maps.Clone(step.tags)
+	tags["check"] = outcome.name
+	value := 0.0
+	if outcome.passed {
+		value = 1
+	}
+	s.samples = append(s.samples, Sample{Metric: "checks", Type: model.MetricRate, Time: now, Value: value, Tags: tags})
+}
+
+// aggregateSamples turns the run-wide counters of a snapshot into samples:
+// the active VUs and what was dropped, sent and received since prev.
+func aggregateSamples(prev, snapshot model.Snapshot) []Sample {
+	at := snapshot.Time
+	return []Sample{
+		{Metric: "vus", Type: model.MetricGauge, Time: at, Value: float64(snapshot.ActiveVUs)},
+		{Metric: "dropped_iterations", Type: model.MetricCounter, Time: at, Value: float64(snapshot.DroppedIterations - prev.DroppedIterations)},
+		{Metric: "data_sent", Type: model.MetricCounter, Time: at, Value: float64(snapshot.DataSent - prev.DataSent)},
+		{Metric: "data_received", Type: model.MetricCounter, Time: at, Value: float64(snapshot.DataReceived - prev.DataReceived)},
+	}
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
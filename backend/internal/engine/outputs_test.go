@@ -0,0 +1,389 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+var sampleTime = time.Unix(1700000000, 500_000_000).UTC()
+
+func testSamples() []Sample {
+	tags := map[string]string{
+		"method":   "GET",
+		"name":     "login page",
+		"scenario": "default",
+		"status":   "200",
+		"url":      "http://example.test/a,b",
+		"team":     "x=1",
+	}
+	return []Sample{
+		{Metric: "http_reqs", Type: model.MetricCounter, Time: sampleTime, Value: 1, Tags: tags},
+		{Metric: "http_req_duration", Type: model.MetricTrend, Time: sampleTime, Value: 12.5, Tags: tags},
+		{Metric: "http_req_failed", Type: model.MetricRate, Time: sampleTime, Value: 0, Tags: tags},
+		{Metric: "checks", Type: model.MetricRate, Time: sampleTime, Value: 1, Tags: map[string]string{"check": "status is 200"}},
+		{Metric: "vus", Type: model.MetricGauge, Time: sampleTime, Value: 3},
+		{Metric: "http_reqs", Type: model.MetricCounter, Time: sampleTime, Value: 1, Tags: tags},
+	}
+}
+
+func openOutput(t *testing.T, dir string, config model.OutputConfig) Output {
+	t.Helper()
+	outputs, err := NewOutputs(dir, []model.OutputConfig{config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outputs[0]
+}
+
+func writeSamples(t *testing.T, output Output, samples []Sample) {
+	t.Helper()
+	if err := output.AddSamples(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := output.AddSnapshot(model.Snapshot{Time: sampleTime, ActiveVUs: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listenUDP returns a local UDP listener and a func that reads its next
+// datagram.
+func listenUDP(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() string {
+		t.Helper()
+		buf := make([]byte, 64*1024)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeSamples(t, openOutput(t, dir, model.OutputConfig{Type: model.OutputJSON, Path: "run.json"}), testSamples())
+
+	file, err := os.Open(filepath.Join(dir, "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line struct {
+			Type   string `json:"type"`
+			Metric string `json:"metric"`
+			Data   struct {
+				Name  string            `json:"name"`
+				Type  string            `json:"type"`
+				Time  time.Time         `json:"time"`
+				Value float64           `json:"value"`
+				Tags  map[string]string `json:"tags"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+
+		switch line.Type {
+		case "Metric":
+			if line.Data.Name != line.Metric {
+				t.Errorf("Metric line names %q, want %q", line.Data.Name, line.Metric)
+			}
+			got = append(got, "Metric "+line.Metric+" "+line.Data.Type)
+		case "Point":
+			if !line.Data.Time.Equal(sampleTime) {
+				t.Errorf("Point time = %v, want %v", line.Data.Time, sampleTime)
+			}
+			if line.Metric == "http_reqs" && line.Data.Tags["name"] != "login page" {
+				t.Errorf("Point tags = %v, want the sample's tags", line.Data.Tags)
+			}
+			got = append(got, "Point "+line.Metric+" "+formatValue(line.Data.Value))
+		default:
+			got = append(got, line.Type)
+		}
+	}
+
+	want := []string{
+		"Metric http_reqs counter", "Point http_reqs 1",
+		"Metric http_req_duration trend", "Point http_req_duration 12.5",
+		"Metric http_req_failed rate", "Point http_req_failed 0",
+		"Metric checks rate", "Point checks 1",
+		"Metric vus gauge", "Point vus 3",
+		"Point http_reqs 1",
+		"Snapshot",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("JSON lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCSVOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeSamples(t, openOutput(t, dir, model.OutputConfig{Type: model.OutputCSV, Path: "run.csv"}), testSamples()[:4])
+
+	file, err := os.Open(filepath.Join(dir, "run.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"metric_name", "timestamp", "metric_value", "check", "error", "method", "name", "scenario", "status", "url", "extra_tags"},
+		{"http_reqs", "1700000000", "1", "", "", "GET", "login page", "default", "200", "http://example.test/a,b", "team=x=1"},
+		{"http_req_duration", "1700000000", "12.5", "", "", "GET", "login page", "default", "200", "http://example.test/a,b", "team=x=1"},
+		{"http_req_failed", "1700000000", "0", "", "", "GET", "login page", "default", "200", "http://example.test/a,b", "team=x=1"},
+		{"checks", "1700000000", "1", "status is 200", "", "", "", "", "", "", ""},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal) {
+		t.Errorf("CSV rows = %q, want %q", rows, want)
+	}
+}
+
+var wantInfluxLines = []string{
+	`http_reqs,method=GET,name=login\ page,scenario=default,status=200,team=x\=1,url=http://example.test/a\,b value=1 1700000000500000000`,
+	`http_req_duration,method=GET,name=login\ page,scenario=default,status=200,team=x\=1,url=http://example.test/a\,b value=12.5 1700000000500000000`,
+	`http_req_failed,method=GET,name=login\ page,scenario=default,status=200,team=x\=1,url=http://example.test/a\,b value=0 1700000000500000000`,
+	`checks,check=status\ is\ 200 value=1 1700000000500000000`,
+	`vus value=3 1700000000500000000`,
+	`http_reqs,method=GET,name=login\ page,scenario=default,status=200,team=x\=1,url=http://example.test/a\,b value=1 1700000000500000000`,
+}
+
+func TestInfluxOutputHTTP(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/write" || r.URL.Query().Get("db") != "k6" {
+			t.Errorf("request %s %s, want POST /write?db=k6", r.Method, r.URL)
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	writeSamples(t, openOutput(t, "", model.OutputConfig{Type: model.OutputInflux, URL: srv.URL + "/write?db=k6"}), testSamples())
+
+	if got, want := <-bodies, strings.Join(wantInfluxLines, "\n")+"\n"; got != want {
+		t.Errorf("line protocol:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestInfluxOutputHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	output := openOutput(t, "", model.OutputConfig{Type: model.OutputInflux, URL: srv.URL + "/write?db=missing"})
+	defer output.Close()
+
+	err := output.AddSamples(testSamples())
+	if err == nil || !strings.Contains(err.Error(), "database not found") {
+		t.Errorf("AddSamples error = %v, want the server's error", err)
+	}
+}
+
+func TestInfluxOutputUDP(t *testing.T) {
+	addr, read := listenUDP(t)
+	writeSamples(t, openOutput(t, "", model.OutputConfig{Type: model.OutputInflux, URL: "udp://" + addr}), testSamples())
+
+	if got, want := read(), strings.Join(wantInfluxLines, "\n"); got != want {
+		t.Errorf("datagram:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestInfluxOutputUDPSplitsDatagrams(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	var samples []Sample
+	for range 100 {
+		samples = append(samples, testSamples()...)
+	}
+	writeSamples(t, openOutput(t, "", model.OutputConfig{Type: model.OutputInflux, URL: "udp://" + addr}), samples)
+
+	var lines []string
+	for len(lines) < len(samples) {
+		datagram := read()
+		if len(datagram) > datagramSize {
+			t.Fatalf("datagram of %d bytes, want at most %d", len(datagram), datagramSize)
+		}
+		lines = append(lines, strings.Split(datagram, "\n")...)
+	}
+	for i, line := range lines {
+		if want := wantInfluxLines[i%len(wantInfluxLines)]; line != want {
+			t.Fatalf("line %d = %q, want %q", i, line, want)
+		}
+	}
+}
+
+func TestStatsDOutput(t *testing.T) {
+	addr, read := listenUDP(t)
+	writeSamples(t, openOutput(t, "", model.OutputConfig{Type: model.OutputStatsD, Address: addr, Prefix: "load."}), testSamples())
+
+	want := strings.Join([]string{
+		"load.http_reqs:1|c",
+		"load.http_req_duration:12.5|ms",
+		"load.checks:1|c",
+		"load.vus:3|g",
+		"load.http_reqs:1|c",
+	}, "\n")
+	if got := read(); got != want {
+		t.Errorf("datagram:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunWritesOutputs(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	outputs, err := NewOutputs(dir, []model.OutputConfig{{Type: model.OutputJSON, Path: "run.json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config model.TestConfig
+	config.Executor = model.PerVUIterations
+	config.VUs = 1
+	config.Iterations = 2
+	script := &model.Script{ID: "script", Steps: []model.Step{{Method: "GET", URL: target.URL, Name: "home"}}}
+	result := NewLoadEngine().Run(context.Background(), map[string]*model.Script{"": script}, config, NewBroadcaster(), outputs)
+	if len(result.OutputErrors) > 0 {
+		t.Fatal(result.OutputErrors)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	for line := range strings.SplitSeq(strings.TrimSpace(string(data)), "\n") {
+		var point struct {
+			Type   string `json:"type"`
+			Metric string `json:"metric"`
+			Data   struct {
+				Value float64           `json:"value"`
+				Tags  map[string]string `json:"tags"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line), &point); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if point.Type != "Point" || point.Metric != "http_reqs" {
+			continue
+		}
+		requests++
+		for key, want := range map[string]string{"method": "GET", "name": "home", "status": "200", "scenario": "default"} {
+			if got := point.Data.Tags[key]; got != want {
+				t.Errorf("http_reqs tag %s = %q, want %q", key, got, want)
+			}
+		}
+	}
+	if requests != 2 {
+		t.Errorf("http_reqs points = %d, want 2", requests)
+	}
+}
+
+// stuckOutput blocks every write until release is closed.
+type stuckOutput struct {
+	release   chan struct{}
+	snapshots int
+}
+
+func (o *stuckOutput) AddSamples(samples []Sample) error {
+	<-o.release
+	return nil
+}
+
+func (o *stuckOutput) AddSnapshot(snapshot model.Snapshot) error {
+	o.snapshots++
+	return nil
+}
+
+func (o *stuckOutput) Close() error { return nil }
+
+// countingOutput counts the snapshots it receives.
+type countingOutput struct {
+	snapshots int
+}
+
+func (o *countingOutput) AddSamples(samples []Sample) error { return nil }
+
+func (o *countingOutput) AddSnapshot(snapshot model.Snapshot) error {
+	o.snapshots++
+	return nil
+}
+
+func (o *countingOutput) Close() error { return nil }
+
+func TestOutputWriterStopsSinkThatFallsBehind(t *testing.T) {
+	stuck := &stuckOutput{release: make(chan struct{})}
+	healthy := &countingOutput{}
+	w := newOutputWriter([]Output{stuck, healthy})
+
+	const batches = 3 * outputBacklog
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for range batches {
+			w.write(outputBatch{})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("write blocked on a stuck output")
+	}
+	close(stuck.release)
+
+	errs := w.close()
+	if len(errs) != 1 || !strings.Contains(errs[0], "behind") {
+		t.Errorf("errors = %q, want the stuck output reported as behind", errs)
+	}
+	if stuck.snapshots > outputBacklog+1 {
+		t.Errorf("stuck output got %d snapshots, want at most the %d queued", stuck.snapshots, outputBacklog+1)
+	}
+	if healthy.snapshots != batches {
+		t.Errorf("healthy output got %d snapshots, want all %d", healthy.snapshots, batches)
+	}
+}
+
+func formatValue(v float64) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k6clone/internal/model"
+)
+
+// StepHeaders returns the headers sent for a step. A body without an explicit
+// Content-Type gets one inferred from its content.
+func StepHeaders(step model.Step) map[string]string {
+	headers := make(map[string]string, len(step.Header)+1)
+	hasContentType := false
+
+	for key, value := range step.Header {
+		headers[key] = value
+		if strings.EqualFold(key, "Content-Type") {
+			hasContentType = true
+		}
+	}
+
+	if step.Body != "" && !hasContentType {
+		headers["Content-Type"] = detectContentType(step.Body)
+	}
+
+	return headers
+}
+
+func detectContentType(body string) string {
+	if json.Valid([]byte(body)) {
+		return "application/json"
+	}
+
+	if strings.Contains(body, "=") && !strings.ContainsAny(body, " \n") {
+		if _, err := url.ParseQuery(body); err == nil {
+			return "application/x-www-form-urlencoded"
+		}
+	}
+
+	return "text/plain; charset=utf-8"
+}
+
+// newRequest builds a fresh request for every iteration so the body reader is
+// never shared. The request keeps GetBody so redirects can replay the body.
+func newRequest(ctx context.Context, step model.Step) (*http.Request, error) {
+	var req *http.Request
+	var err error
+
+	if step.Body != "" {
+		req, err = http.NewRequestWithContext(ctx, step.Method, step.URL, strings.NewReader(step.Body))
+	} else {
+		req, err = http.NewRequestWithContext(ctx, step.Method, step.URL, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range StepHeaders(step) {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"regexp"
+	"strings"
+
+	"k6clone/internal/model"
+)
+
+var metricPattern = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)(?:\{(.*)\})?$`)
+
+var errInvalidMetric = errors.New("invalid metric")
+
+// ParseMetric splits a metric reference such as
+// "http_req_duration{name:login,status:200}" into the metric name and the
+// tags its samples must carry, as in k6's sub-metrics.
+func ParseMetric(metric string) (string, map[string]string, error) {
+	m := metricPattern.FindStringSubmatch(strings.TrimSpace(metric))
+	if m == nil {
+		return "", nil, errInvalidMetric
+	}
+	if !strings.Contains(metric, "{") {
+		return m[1], nil, nil
+	}
+
+	filter := make(map[string]string)
+	for part := range strings.SplitSeq(m[2], ",") {
+		key, value, ok := strings.Cut(part, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return "", nil, errInvalidMetric
+		}
+		filter[key] = strings.TrimSpace(value)
+	}
+	return m[1], filter, nil
+}
+
+// tagKey renders a tag set canonically; encoding/json sorts map keys.
+func tagKey(tags map[string]string) string {
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+// subMetric is a tag filter used by at least one threshold. Samples matching
+// it are also recorded into a shard of their own, keyed by the filter.
+type subMetric struct {
+	key    string
+	filter map[string]string
+}
+
+func subMetrics(thresholds []model.Threshold) []subMetric {
+	var subs []subMetric
+	seen := make(map[string]bool)
+
+	for _, threshold := range thresholds {
+		_, filter, err := ParseMetric(threshold.Metric)
+		if err != nil || filter == nil {
+			continue
+		}
+		key := tagKey(filter)
+		if !seen[key] {
+			seen[key] = true
+			subs = append(subs, subMetric{key: key, filter: filter})
+		}
+	}
+	return subs
+}
+
+// scenarioSubs returns the keys of the sub-metrics whose filter a
+// scenario's tags alone satisfy.
+func scenarioSubs(subs []subMetric, tags map[string]string) []string {
+	var keys []string
+	for _, sub := range subs {
+		if sub.matches(tags, "", "") {
+			keys = append(keys, sub.key)
+		}
+	}
+	return keys
+}
+
+// matches reports whether a sample with the given tags, plus the optional
+// tag only known once the sample is taken (status or check), passes the
+// filter.
+func (m subMetric) matches(tags map[string]string, extraKey, extraValue string) bool {
+	for key, want := range m.filter {
+		value, ok := tags[key]
+		if key == extraKey && extraKey != "" {
+			value, ok = extraValue, true
+		}
+		if !ok || value != want {
+			return false
+		}
+	}
+	return true
+}
+
+// scenarioTags returns the tags every sample of a scenario carries: the run's
+// tags, overridden by the scenario's, plus the scenario name.
+func scenarioTags(config model.TestConfig, name string, scenario model.Scenario) map[string]string {
+	tags := maps.Clone(config.Tags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	maps.Copy(tags, scenario.Tags)
+	tags["scenario"] = name
+	return tags
+}
+
+// stepTags adds a step's system tags (name, method, url) and its own tags to
+// the scenario's tags.
+func stepTags(base map[string]string, step model.Step) map[string]string {
+	tags := maps.Clone(base)
+	tags["name"] = EndpointName(step)
+	tags["method"] = strings.ToUpper(step.Method)
+	tags["url"] = step.URL
+	maps.Copy(tags, step.Tags)
+	return tags
+}
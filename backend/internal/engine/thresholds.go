@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+var (
+	conditionPattern   = regexp.MustCompile(`^\s*([a-z]+(?:\(\s*[0-9.]+\s*\))?)\s*(<=|>=|==|!=|<|>)\s*(-?[0-9.]+)\s*$`)
+	percentilePattern  = regexp.MustCompile(`^p\(\s*([0-9.]+)\s*\)$`)
+	errUnknownMetric   = errors.New("unknown metric")
+	errUnsupportedAggr = errors.New("unsupported aggregation")
+)
+
+type metricView struct {
+	durations   *histogram
+	responses   *histogram
+	timings     *[timingCount]histogram
+	counts      counts
+	checkPasses int
+	checkFails  int
+	custom      map[string]*customMetric
+	subs        map[string]*shard
+	declared    map[string]model.MetricType
+	elapsed     time.Duration
+}
+
+// value resolves an aggregation of one of the built-in metrics, using k6's
+// metric names. Durations are in milliseconds and rates are 0..1 fractions,
+// except count "rate" aggregations which are per second.
+func (v metricView) value(metric, aggregation string) (float64, error) {
+	name, filter, err := ParseMetric(metric)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, metric)
+	}
+	if filter != nil {
+		sub, ok := v.subs[tagKey(filter)]
+		if !ok {
+			sub = newShard()
+		}
+		return sub.view(v.elapsed, v.declared).value(name, aggregation)
+	}
+
+	perSecond := func(n int) float64 {
+		if v.elapsed <= 0 {
+			return 0
+		}
+		return float64(n) / v.elapsed.Seconds()
+	}
+	ratio := func(part, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(part) / float64(total)
+	}
+
+	if i := slices.Index(timingMetrics[:], metric); i >= 0 {
+		return trendValue(&v.timings[i], aggregation)
+	}
+
+	switch metric {
+	case "http_req_duration":
+		return trendValue(v.durations, aggregation)
+	case "http_req_response_time":
+		return trendValue(v.responses, aggregation)
+	case "http_req_failed":
+		if aggregation == "rate" {
+			return ratio(v.counts.failure, v.counts.total), nil
+		}
+	case "checks":
+		if aggregation == "rate" {
+			return ratio(v.checkPasses, v.checkPasses+v.checkFails), nil
+		}
+	case "http_reqs", "iterations", "dropped_iterations", "data_sent", "data_received":
+		n := map[string]int{
+			"http_reqs":          v.counts.total,
+			"iterations":         v.counts.iterations,
+			"dropped_iterations": v.counts.dropped,
+			"data_sent":          v.counts.sent,
+			"data_received":      v.counts.received,
+		}[metric]
+
+		switch aggregation {
+		case "count":
+			return float64(n), nil
+		case "rate":
+			return perSecond(n), nil
+		}
+	default:
+		custom, ok := v.custom[metric]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", errUnknownMetric, metric)
+		}
+		return custom.value(aggregation, v.elapsed)
+	}
+
+	return 0, fmt.Errorf("%w: %s for %s", errUnsupportedAggr, aggregation, metric)
+}
+
+func trendValue(durations *histogram, aggregation string) (float64, error) {
+	switch aggregation {
+	case "avg":
+		return durations.mean(), nil
+	case "min":
+		return millis(durations.min), nil
+	case "max":
+		return millis(durations.max), nil
+	case "med":
+		return durations.percentile(50), nil
+	case "count":
+		return float64(durations.count), nil
+	}
+
+	if m := percentilePattern.FindStringSubmatch(aggregation); m != nil {
+		p, err := strconv.ParseFloat(m[1], 64)
+		if err == nil && p >= 0 && p <= 100 {
+			return durations.percentile(p), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", errUnsupportedAggr, aggregation)
+}
+
+// NormalizeThreshold fills Aggregation, Operator and Value from a k6-style
+// Condition such as "p(95)<500" (or the reverse) and checks that built-in
+// metrics support the aggregation.
+func NormalizeThreshold(threshold model.Threshold) (model.Threshold, error) {
+	if threshold.Condition != "" {
+		m := conditionPattern.FindStringSubmatch(threshold.Condition)
+		if m == nil {
+			return threshold, fmt.Errorf("invalid threshold condition %q", threshold.Condition)
+		}
+
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return threshold, fmt.Errorf("invalid threshold value %q", m[3])
+		}
+
+		threshold.Aggregation = strings.ReplaceAll(m[1], " ", "")
+		threshold.Operator = m[2]
+		threshold.Value = value
+	}
+
+	switch threshold.Operator {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return threshold, fmt.Errorf("invalid threshold operator %q", threshold.Operator)
+	}
+
+	// An empty view resolves every supported metric/aggregation pair, so
+	// this only rejects unsupported combinations. Other metric names may be
+	// custom metrics, which ValidateThresholds checks against the scripts.
+	_, err := newShard().view(0, nil).value(threshold.Metric, threshold.Aggregation)
+	if err != nil && !errors.Is(err, errUnknownMetric) {
+		return threshold, err
+	}
+
+	threshold.Condition = ThresholdCondition(threshold)
+	return threshold, nil
+}
+
+// ThresholdCondition renders a normalized threshold in k6's syntax.
+func ThresholdCondition(threshold model.Threshold) string {
+	return threshold.Aggregation + threshold.Operator + strconv.FormatFloat(threshold.Value, 'f', -1, 64)
+}
+
+func evaluateThreshold(threshold model.Threshold, view metricView) model.ThresholdResult {
+	actual, err := view.value(threshold.Metric, threshold.Aggregation)
+
+	return model.ThresholdResult{
+		Threshold: threshold,
+		Actual:    actual,
+		Passed:    err == nil && compare(actual, threshold.Operator, threshold.Value),
+	}
+}
+
+func evaluateThresholds(thresholds []model.Threshold, view metricView) ([]model.ThresholdResult, bool) {
+	results := make([]model.ThresholdResult, 0, len(thresholds))
+	passed := true
+
+	for _, threshold := range thresholds {
+		result := evaluateThreshold(threshold, view)
+		passed = passed && result.Passed
+		results = append(results, result)
+	}
+	return results, passed
+}
+
+func compare(actual float64, operator string, value float64) bool {
+	switch operator {
+	case "<":
+		return actual < value
+	case "<=":
+		return actual <= value
+	case ">":
+		return actual > value
+	case ">=":
+		return actual >= value
+	case "==":
+		return actual == value
+	case "!=":
+		return actual != value
+	}
+	return false
+}
+
+func normalizeThresholds(thresholds []model.Threshold) []model.Threshold {
+	normalized := make([]model.Threshold, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		if t, err := NormalizeThreshold(threshold); err == nil {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized
+}
+
+// watchThresholds re-evaluates the abortOnFail thresholds every interval once
+// their delayAbortEval has passed, and calls abort for the first one that
+// fails.
+func watchThresholds(
+	ctx context.Context,
+	thresholds []model.Threshold,
+	view func(elapsed time.Duration) metricView,
+	startedAt time.Time,
+	abort func(reason string),
+) {
+	var watched []model.Threshold
+	for _, threshold := range thresholds {
+		if threshold.AbortOnFail {
+			watched = append(watched, threshold)
+		}
+	}
+	if len(watched) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(startedAt)
+			view := view(elapsed)
+
+			for _, threshold := range watched {
+				if elapsed < time.Duration(threshold.DelayAbortEval)*time.Second {
+					continue
+				}
+
+				if result := evaluateThreshold(threshold, view); !result.Passed {
+					abort(fmt.Sprintf("threshold %s %s crossed (actual %g)",
+						threshold.Metric, threshold.Condition, result.Actual))
+					return
+				}
+			}
+		}
+	}
+}
+
+// mergeCollectors merges in scenario name order so check results keep a
+// stable order across runs.
+func mergeCollectors(collectors map[string]*collector) *shard {
+	total := newShard()
+	for _, name := range slices.Sorted(maps.Keys(collectors)) {
+		total.merge(collectors[name].aggregate())
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	timingBlocked = iota
+	timingConnecting
+	timingTLSHandshaking
+	timingSending
+	timingWaiting
+	timingReceiving
+	timingCount
+)
+
+// timingMetrics names the phases of a request after k6's metrics.
+var timingMetrics = [timingCount]string{
+	"http_req_blocked",
+	"http_req_connecting",
+	"http_req_tls_handshaking",
+	"http_req_sending",
+	"http_req_waiting",
+	"http_req_receiving",
+}
+
+type timings [timingCount]time.Duration
+
+// requestTrace records when each phase of a request starts and ends. Dial
+// callbacks can fire from other goroutines, and only the first of each event
+// counts.
+type requestTrace struct {
+	mu sync.Mutex
+
+	getConn      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func traceRequest(req *http.Request) (*http.Request, *requestTrace) {
+	t := &requestTrace{}
+	mark := func(at *time.Time) {
+		now := time.Now()
+		t.mu.Lock()
+		if at.IsZero() {
+			*at = now
+		}
+		t.mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		GetConn:              func(string) { mark(&t.getConn) },
+		ConnectStart:         func(string, string) { mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { mark(&t.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// timings splits a request that finished reading its body at end into k6's
+// phases. Blocked covers the wait for a connection including DNS lookup;
+// phases that did not happen, such as connecting on a reused connection, are
+// zero.
+func (t *requestTrace) timings(end time.Time) timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	connected := t.gotConn
+	if !t.connectStart.IsZero() {
+		connected = t.connectStart
+	}
+
+	return timings{
+		timingBlocked:        between(t.getConn, connected),
+		timingConnecting:     between(t.connectStart, t.connectDone),
+		timingTLSHandshaking: between(t.tlsStart, t.tlsDone),
+		timingSending:        between(t.gotConn, t.wroteRequest),
+		timingWaiting:        between(t.wroteRequest, t.firstByte),
+		timingReceiving:      between(t.firstByte, end),
+	}
+}
+
+// duration is http_req_duration as k6 defines it: sending, waiting and
+// receiving, without the time spent getting a connection.
+func (t timings) duration() time.Duration {
+	return t[timingSending] + t[timingWaiting] + t[timingReceiving]
+}
+
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+// Span is the client span of one traced request.
+type Span struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Status  int
+	Error   string
+	Tags    map[string]string
+}
+
+// SpanOutput is an Output that also receives the spans of traced requests.
+type SpanOutput interface {
+	Output
+	AddSpans(spans []Span) error
+}
+
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// newSpanContext starts a new trace; every traced request is the root of its
+// own trace, as with k6's tracing module.
+func newSpanContext() spanContext {
+	var c spanContext
+	binary.BigEndian.PutUint64(c.traceID[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(c.traceID[8:], rand.Uint64())
+	binary.BigEndian.PutUint64(c.spanID[:], rand.Uint64()|1)
+	return c
+}
+
+// traceparent renders the W3C Trace Context header of a sampled span.
+func (c spanContext) traceparent() string {
+	return "00-" + hex.EncodeToString(c.traceID[:]) + "-" + hex.EncodeToString(c.spanID[:]) + "-01"
+}
+
+// tracingEnabled reports whether any output of a run asks for request traces.
+func tracingEnabled(outputs []model.OutputConfig) bool {
+	return slices.ContainsFunc(outputs, func(output model.OutputConfig) bool {
+		return output.Type == model.OutputOTLP && output.Traces
+	})
+}
+
+func (s *shard) addSpan(span Span) {
+	s.mu.Lock()
+	s.spans = append(s.spans, span)
+	s.mu.Unlock()
+}
+
+func requestSpan(step stepMeta, c spanContext, start, end time.Time, status int, failure *requestFailure) Span {
+	span := Span{
+		TraceID: c.traceID,
+		SpanID:  c.spanID,
+		Name:    step.method + " " + step.name,
+		Start:   start,
+		End:     end,
+		Status:  status,
+		Tags:    step.tags,
+	}
+	if failure != nil {
+		span.Error = failure.category
+	}
+	return span
+}
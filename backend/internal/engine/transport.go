@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newClient returns a client whose connections count the bytes they carry
+// into metrics, so data totals include headers, TLS and protocol overhead as
+// they appear on the wire.
+func newClient(metrics *collector) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, metrics: metrics}, nil
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	metrics *collector
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.metrics.dataReceived.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.metrics.dataSent.Add(int64(n))
+	return n, err
+}
@@ -2,9 +2,16 @@ package generator
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 
+	"k6clone/internal/engine"
 	"k6clone/internal/model"
 )
 
@@ -22,31 +29,116 @@ type K6JSInput struct {
 func (g *K6JSGenerator) Generate(input *K6JSInput) (string, error) {
 	const tpl = `import http from "k6/http";
 import { check, sleep } from "k6";
+{{- if .MetricImports}}
+import { {{join .MetricImports ", "}} } from "k6/metrics";
+{{- end}}
+{{- if .MetricDecls}}
+{{range .MetricDecls}}
+const {{.Var}} = new {{.Class}}({{json .Name}});
+{{- end}}
+{{- end}}
 
 export const options = {
+{{- if .DiscardResponseBodies}}
+  discardResponseBodies: true,
+{{- end}}
+{{- if .Tags}}
+  tags: {{json .Tags}},
+{{- end}}
+{{- if eq .Executor "constant-vus"}}
   vus: {{.VUs}},
   duration: "{{.Duration}}s",
+{{- else}}
+  scenarios: {
+    main: {
+      executor: "{{.Executor}}",
+{{- if eq .Executor "ramping-vus"}}
+      startVUs: 0,
+{{- end}}
+{{- if eq .Executor "constant-arrival-rate"}}
+      rate: {{.Rate}},
+      duration: "{{.Duration}}s",
+{{- end}}
+{{- if eq .Executor "ramping-arrival-rate"}}
+      startRate: {{.StartRate}},
+{{- end}}
+{{- if or (eq .Executor "constant-arrival-rate") (eq .Executor "ramping-arrival-rate")}}
+      timeUnit: "{{.TimeUnit}}",
+      preAllocatedVUs: {{.PreAllocatedVUs}},
+      maxVUs: {{.MaxVUs}},
+{{- end}}
+{{- if or (eq .Executor "per-vu-iterations") (eq .Executor "shared-iterations")}}
+      vus: {{.VUs}},
+      iterations: {{.Iterations}},
+{{- if .MaxDuration}}
+      maxDuration: "{{.MaxDuration}}s",
+{{- end}}
+{{- end}}
+{{- if .Stages}}
+      stages: [
+{{- range .Stages}}
+        { duration: "{{.Duration}}s", target: {{.Target}} },
+{{- end}}
+      ],
+{{- end}}
+    },
+  },
+{{- end}}
+{{- if .Thresholds}}
   thresholds: {
-    http_req_duration: ['p(95)<2000', 'p(99)<5000'],
-    http_req_failed: ['rate<0.1'],
+{{- range .Thresholds}}
+    {{jsKey .Metric}}: [{{range $j, $entry := .Entries}}{{if $j}}, {{end}}{{$entry}}{{end}}],
+{{- end}}
   },
+{{- end}}
 };
 
 export default function () {
 {{range $i, $step := .Steps}}
   // Step {{add $i 1}}: {{$step.Method}} {{$step.URL}}
-  const res{{$i}} = http.{{lower $step.Method}}("{{$step.URL}}"{{if $step.Body}}, {{$step.Body}}{{end}});
+{{- $headers := headers $step}}
+{{- $tags := requestTags $step}}
+{{- if or $step.Body $headers $tags}}
+  const res{{$i}} = http.request("{{$step.Method}}", {{json $step.URL}}, {{if $step.Body}}{{json $step.Body}}{{else}}null{{end}}, {
+    headers: {{json $headers}},
+{{- if $tags}}
+    tags: {{json $tags}},
+{{- end}}
+  });
+{{- else}}
+  const res{{$i}} = http.{{lower $step.Method}}("{{$step.URL}}");
+{{- end}}
   check(res{{$i}}, {
-    "status is 2xx": (r) => r.status >= 200 && r.status < 300,
+{{- range checks $step}}
+    {{json (checkName .)}}: (r) => {{checkExpr .}},
+{{- end}}
   });
+{{- range $step.Metrics}}
+  {{metricVar .Name}}.add({{metricExpr $i $step .}}{{if .Tags}}, {{json .Tags}}{{end}});
+{{- end}}
 {{end}}
   sleep(1);
 }
 `
 	type view struct {
-		VUs      int
-		Duration int
-		Steps    []model.Step
+		Executor        model.Executor
+		VUs             int
+		Duration        int
+		Stages          []model.Stage
+		Rate            int
+		StartRate       int
+		TimeUnit        string
+		PreAllocatedVUs int
+		MaxVUs          int
+		Iterations      int
+		MaxDuration     int
+		Thresholds      []thresholdView
+		Steps           []model.Step
+		MetricImports   []string
+		MetricDecls     []metricDecl
+
+		DiscardResponseBodies bool
+		Tags                  map[string]string
 	}
 
 	funcMap := template.FuncMap{
@@ -56,6 +148,16 @@ export default function () {
 		"add": func(a, b int) int {
 			return a + b
 		},
+		"headers":     engine.StepHeaders,
+		"checks":      engine.StepChecks,
+		"checkName":   engine.CheckName,
+		"checkExpr":   checkExpr,
+		"json":        toJSON,
+		"join":        strings.Join,
+		"metricVar":   metricVar,
+		"metricExpr":  metricExpr,
+		"jsKey":       jsKey,
+		"requestTags": requestTags,
 	}
 
 	t, err := template.New("k6").Funcs(funcMap).Parse(tpl)
@@ -63,12 +165,211 @@ export default function () {
 		return "", err
 	}
 
+	timeUnit := input.Config.TimeUnit
+	if timeUnit == "" {
+		timeUnit = "1s"
+	}
+	preAllocated, maxVUs := engine.VUPool(input.Config.ExecutorConfig)
+	imports, decls := metricDecls(input.Script.Steps)
+
 	var buf bytes.Buffer
 	err = t.Execute(&buf, view{
-		VUs:      input.Config.VUs,
-		Duration: input.Config.Duration,
-		Steps:    input.Script.Steps,
+		Executor:        engine.ResolveExecutor(input.Config.ExecutorConfig),
+		VUs:             input.Config.VUs,
+		Duration:        input.Config.Duration,
+		Stages:          input.Config.Stages,
+		Rate:            input.Config.Rate,
+		StartRate:       input.Config.StartRate,
+		TimeUnit:        timeUnit,
+		PreAllocatedVUs: preAllocated,
+		MaxVUs:          maxVUs,
+		Iterations:      input.Config.Iterations,
+		MaxDuration:     input.Config.MaxDuration,
+		Thresholds:      thresholdViews(input.Config.Thresholds),
+		Steps:           input.Script.Steps,
+		MetricImports:   imports,
+		MetricDecls:     decls,
+
+		DiscardResponseBodies: input.Config.DiscardResponseBodies,
+		Tags:                  input.Config.Tags,
 	})
 
 	return buf.String(), err
-}
\ No newline at end of file
+}
+
+type thresholdView struct {
+	Metric  string
+	Entries []string
+}
+
+// thresholdViews groups thresholds per metric as k6 expects them, using the
+// object form for thresholds that abort the run. Like the engine, a config
+// without thresholds gets none.
+func thresholdViews(thresholds []model.Threshold) []thresholdView {
+	var views []thresholdView
+	index := make(map[string]int)
+
+	for _, threshold := range thresholds {
+		normalized, err := engine.NormalizeThreshold(threshold)
+		if err != nil {
+			continue
+		}
+
+		entry := "'" + normalized.Condition + "'"
+		if normalized.AbortOnFail {
+			entry = fmt.Sprintf("{ threshold: %s, abortOnFail: true, delayAbortEval: '%ds' }",
+				entry, normalized.DelayAbortEval)
+		}
+
+		i, ok := index[normalized.Metric]
+		if !ok {
+			i = len(views)
+			index[normalized.Metric] = i
+			views = append(views, thresholdView{Metric: normalized.Metric})
+		}
+		views[i].Entries = append(views[i].Entries, entry)
+	}
+
+	return views
+}
+
+// checkExpr renders a check as the body of a k6 check function so the exported
+// script asserts exactly what the engine evaluates.
+func checkExpr(check model.Check) string {
+	quote := func(s string) string {
+		data, _ := toJSON(s)
+		return data
+	}
+
+	switch check.Type {
+	case model.CheckStatus:
+		if len(check.StatusCodes) == 0 {
+			return "r.status >= 200 && r.status < 300"
+		}
+		codes := make([]string, len(check.StatusCodes))
+		for i, code := range check.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		return "[" + strings.Join(codes, ", ") + "].includes(r.status)"
+	case model.CheckHeader:
+		header := "r.headers[" + quote(http.CanonicalHeaderKey(check.Header)) + "]"
+		if check.Value == "" {
+			return header + " !== undefined"
+		}
+		return header + " === " + quote(check.Value)
+	case model.CheckBodyContains:
+		return "String(r.body).includes(" + quote(check.Value) + ")"
+	case model.CheckBodyMatches:
+		return "new RegExp(" + quote(check.Value) + ").test(String(r.body))"
+	case model.CheckJSONPath:
+		path := strings.Join(engine.JSONPathSegments(check.Path), ".")
+		return "String(r.json(" + quote(path) + ")) === " + quote(check.Value)
+	case model.CheckMaxDuration:
+		return "r.timings.duration < " + strconv.FormatInt(check.MaxDurationMs, 10)
+	}
+	return "false"
+}
+
+// toJSON encodes v as a JavaScript literal without HTML escaping.
+func toJSON(v any) (string, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+var metricClasses = map[model.MetricType]string{
+	model.MetricCounter: "Counter",
+	model.MetricGauge:   "Gauge",
+	model.MetricRate:    "Rate",
+	model.MetricTrend:   "Trend",
+}
+
+type metricDecl struct {
+	Var   string
+	Class string
+	Name  string
+}
+
+// metricDecls returns the k6/metrics classes to import and one declaration
+// per custom metric the steps record, in name order.
+func metricDecls(steps []model.Step) ([]string, []metricDecl) {
+	types := make(map[string]model.MetricType)
+	for _, step := range steps {
+		for _, metric := range step.Metrics {
+			types[metric.Name] = metric.Type
+		}
+	}
+
+	var imports []string
+	var decls []metricDecl
+	for _, name := range slices.Sorted(maps.Keys(types)) {
+		class := metricClasses[types[name]]
+		if !slices.Contains(imports, class) {
+			imports = append(imports, class)
+		}
+		decls = append(decls, metricDecl{Var: metricVar(name), Class: class, Name: name})
+	}
+	slices.Sort(imports)
+
+	return imports, decls
+}
+
+func metricVar(name string) string {
+	return "metric_" + name
+}
+
+// metricExpr renders the value a step metric adds for the response res<index>.
+func metricExpr(index int, step model.Step, metric model.StepMetric) string {
+	res := "res" + strconv.Itoa(index)
+
+	switch metric.Source {
+	case model.SourceJSONPath:
+		path, _ := toJSON(strings.Join(engine.JSONPathSegments(metric.Path), "."))
+		return "Number(" + res + ".json(" + path + "))"
+	case model.SourceCheck:
+		for _, check := range engine.StepChecks(step) {
+			if engine.CheckName(check) == metric.Check {
+				return "((r) => " + checkExpr(check) + ")(" + res + ")"
+			}
+		}
+		return "false"
+	case model.SourceDuration:
+		return res + ".timings.duration"
+	case model.SourceStatus:
+		return res + ".status"
+	}
+
+	if metric.Value != nil {
+		return strconv.FormatFloat(*metric.Value, 'f', -1, 64)
+	}
+	return "1"
+}
+
+// requestTags returns the tags set on a step's requests: its own tags and,
+// for named steps, the name tag.
+func requestTags(step model.Step) map[string]string {
+	if step.Name == "" {
+		return step.Tags
+	}
+
+	tags := maps.Clone(step.Tags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags["name"] = step.Name
+	return tags
+}
+
+// jsKey quotes object keys that are not plain identifiers, such as
+// sub-metrics like http_req_duration{name:login}.
+func jsKey(key string) (string, error) {
+	if strings.ContainsAny(key, "{}:, ") {
+		return toJSON(key)
+	}
+	return key, nil
+}
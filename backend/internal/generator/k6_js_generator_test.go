@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"k6clone/internal/model"
+)
+
+func generateScript(t *testing.T, config model.TestConfig) string {
+	t.Helper()
+	script := &model.Script{ID: "script", Steps: []model.Step{{Method: "GET", URL: "http://example.test"}}}
+	config.Executor = model.ConstantVUs
+	config.VUs = 1
+	config.Duration = 10
+	js, err := NewK6JSGenerator().Generate(&K6JSInput{Script: script, Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return js
+}
+
+func TestGenerateWithoutThresholds(t *testing.T) {
+	// The engine checks no thresholds a config does not set, so neither
+	// does the exported script.
+	if js := generateScript(t, model.TestConfig{}); strings.Contains(js, "thresholds") {
+		t.Errorf("script has thresholds:\n%s", js)
+	}
+}
+
+func TestGenerateThresholds(t *testing.T) {
+	js := generateScript(t, model.TestConfig{Thresholds: []model.Threshold{
+		{Metric: "http_req_duration", Condition: "p(95)<500"},
+		{Metric: "http_req_duration", Condition: "p(99)<900", AbortOnFail: true, DelayAbortEval: 5},
+		{Metric: "http_req_failed", Condition: "rate<0.01"},
+	}})
+
+	for _, want := range []string{
+		"  thresholds: {\n",
+		"    http_req_duration: ['p(95)<500', { threshold: 'p(99)<900', abortOnFail: true, delayAbortEval: '5s' }],\n",
+		"    http_req_failed: ['rate<0.01'],\n",
+	} {
+		if !strings.Contains(js, want) {
+			t.Errorf("script lacks %q:\n%s", want, js)
+		}
+	}
+}
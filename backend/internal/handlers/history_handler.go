@@ -3,7 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
+	"k6clone/internal/model"
 	"k6clone/internal/repository"
 )
 
@@ -15,8 +17,44 @@ func NewHistoryHandler(r repository.TestResultRepository) *HistoryHandler {
 	return &HistoryHandler{repo: r}
 }
 
-func (h *HistoryHandler) GetHistory(w http.ResponseWriter, _ *http.Request) {
-	results := h.repo.FindAll()
+// GetHistory lists stored results, optionally only those of one script
+// (?scriptId=) or whose run tags include every ?tag=key:value given.
+func (h *HistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	var results []model.TestResult
+	if scriptID := r.URL.Query().Get("scriptId"); scriptID != "" {
+		results = h.repo.FindByScriptID(scriptID)
+	} else {
+		results = h.repo.FindAll()
+	}
+
+	filter := make(map[string]string)
+	for _, pair := range r.URL.Query()["tag"] {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			http.Error(w, "invalid tag filter", http.StatusBadRequest)
+			return
+		}
+		filter[key] = value
+	}
+
+	if len(filter) > 0 {
+		var tagged []model.TestResult
+		for _, result := range results {
+			if hasTags(result.Config.Tags, filter) {
+				tagged = append(tagged, result)
+			}
+		}
+		results = tagged
+	}
 
 	json.NewEncoder(w).Encode(results)
 }
+
+func hasTags(tags, filter map[string]string) bool {
+	for key, want := range filter {
+		if value, ok := tags[key]; !ok || value != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k6clone/internal/model"
+	"k6clone/internal/service"
+)
+
+// summaryQuantiles are the quantiles exported for each duration metric.
+var summaryQuantiles = []struct {
+	label string
+	value func(model.TrendStats) float64
+}{
+	{"0.5", func(s model.TrendStats) float64 { return s.Med }},
+	{"0.9", func(s model.TrendStats) float64 { return s.P90 }},
+	{"0.95", func(s model.TrendStats) float64 { return s.P95 }},
+	{"0.99", func(s model.TrendStats) float64 { return s.P99 }},
+}
+
+type MetricsHandler struct {
+	tests   *service.TestService
+	scripts *service.ScriptService
+}
+
+func NewMetricsHandler(t *service.TestService, s *service.ScriptService) *MetricsHandler {
+	return &MetricsHandler{tests: t, scripts: s}
+}
+
+// GetMetrics serves server stats and the metrics of running tests in the
+// Prometheus text exposition format.
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m := newExposition()
+	h.serverMetrics(m)
+
+	active := h.tests.ActiveTests()
+	slices.SortFunc(active, func(a, b model.TestRun) int {
+		return strings.Compare(a.TestID, b.TestID)
+	})
+	for _, run := range active {
+		snapshot, scenarios, ok := h.tests.LiveMetrics(run.TestID)
+		if ok {
+			runMetrics(m, run.TestID, snapshot, scenarios)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeTo(w)
+}
+
+func (h *MetricsHandler) serverMetrics(m *exposition) {
+	completed := h.tests.CompletedTests()
+	for _, status := range slices.Sorted(maps.Keys(completed)) {
+		m.add("k6_server_tests_total", "counter", "Test runs completed since the server started, by final status.",
+			float64(completed[status]), "status", string(status))
+	}
+
+	active := map[model.RunStatus]int{model.Queued: 0, model.Running: 0}
+	for _, run := range h.tests.ActiveTests() {
+		active[run.Status]++
+	}
+	for _, status := range slices.Sorted(maps.Keys(active)) {
+		m.add("k6_server_runs_active", "gauge", "Test runs queued or running.",
+			float64(active[status]), "status", string(status))
+	}
+
+	if scripts, err := h.scripts.GetAll(); err == nil {
+		m.add("k6_server_scripts", "gauge", "Stored scripts.", float64(len(scripts)))
+	}
+}
+
+func runMetrics(m *exposition, testID string, snapshot model.Snapshot, scenarios map[string]model.ScenarioResult) {
+	m.add("k6_vus", "gauge", "Active virtual users.", float64(snapshot.ActiveVUs), "test_id", testID)
+
+	for _, name := range slices.Sorted(maps.Keys(scenarios)) {
+		summary := scenarios[name].Summary
+		labels := []string{"test_id", testID, "scenario", name}
+
+		m.add("k6_http_reqs_total", "counter", "HTTP requests made.",
+			float64(summary.TotalRequests), labels...)
+		m.add("k6_http_req_failed_total", "counter", "HTTP requests that failed.",
+			float64(summary.Failure), labels...)
+		m.add("k6_iterations_total", "counter", "Iterations completed.",
+			float64(summary.Iterations), labels...)
+		m.add("k6_dropped_iterations_total", "counter", "Iterations dropped for lack of a free VU.",
+			float64(summary.DroppedIterations), labels...)
+		m.add("k6_data_sent_bytes_total", "counter", "Bytes sent on the wire.",
+			float64(summary.DataSent), labels...)
+		m.add("k6_data_received_bytes_total", "counter", "Bytes received on the wire.",
+			float64(summary.DataReceived), labels...)
+
+		m.summary("k6_http_req_duration_seconds", "HTTP request service time.",
+			summary.Latency, labels...)
+		m.summary("k6_http_req_response_time_seconds", "HTTP response time including queueing delay.",
+			summary.ResponseTime, labels...)
+
+		for _, code := range slices.Sorted(maps.Keys(summary.StatusCodes)) {
+			m.add("k6_http_responses_total", "counter", "HTTP responses, by status code.",
+				float64(summary.StatusCodes[code]), append(labels, "status", strconv.Itoa(code))...)
+		}
+
+		for _, check := range summary.Checks {
+			checkLabels := append(slices.Clone(labels), "check", check.Name)
+			m.add("k6_checks_total", "counter", "Check outcomes.",
+				float64(check.Passes), append(checkLabels, "result", "pass")...)
+			m.add("k6_checks_total", "counter", "Check outcomes.",
+				float64(check.Fails), append(checkLabels, "result", "fail")...)
+		}
+
+		for _, step := range summary.Steps {
+			stepLabels := append(slices.Clone(labels),
+				"step", step.Name, "index", strconv.Itoa(step.Index), "method", step.Method)
+			m.add("k6_step_http_reqs_total", "counter", "HTTP requests made, by step.",
+				float64(step.Requests), stepLabels...)
+			m.add("k6_step_http_req_failed_total", "counter", "HTTP requests that failed, by step.",
+				float64(step.Failures), stepLabels...)
+			m.summary("k6_step_http_req_duration_seconds", "HTTP request service time, by step.",
+				step.Latency, stepLabels...)
+		}
+	}
+}
+
+type metricFamily struct {
+	help    string
+	kind    string
+	samples []string
+}
+
+// exposition collects samples by family, since the text format requires
+// every sample of a family to follow its HELP and TYPE lines.
+type exposition struct {
+	order    []string
+	families map[string]*metricFamily
+}
+
+func newExposition() *exposition {
+	return &exposition{families: make(map[string]*metricFamily)}
+}
+
+func (e *exposition) family(name, kind, help string) *metricFamily {
+	family, ok := e.families[name]
+	if !ok {
+		family = &metricFamily{help: help, kind: kind}
+		e.families[name] = family
+		e.order = append(e.order, name)
+	}
+	return family
+}
+
+// add records a sample; labels are name/value pairs.
+func (e *exposition) add(name, kind, help string, value float64, labels ...string) {
+	family := e.family(name, kind, help)
+	family.samples = append(family.samples, sampleLine(name, value, labels))
+}
+
+// summary records a duration trend, given in milliseconds, as a summary in
+// seconds.
+func (e *exposition) summary(name, help string, stats model.TrendStats, labels ...string) {
+	family := e.family(name, "summary", help)
+	for _, q := range summaryQuantiles {
+		family.samples = append(family.samples, sampleLine(name, q.value(stats)/1000,
+			append(slices.Clone(labels), "quantile", q.label)))
+	}
+	family.samples = append(family.samples,
+		sampleLine(name+"_sum", stats.Avg*float64(stats.Count)/1000, labels),
+		sampleLine(name+"_count", float64(stats.Count), labels))
+}
+
+func (e *exposition) writeTo(w http.ResponseWriter) {
+	var b strings.Builder
+	for _, name := range e.order {
+		family := e.families[name]
+		b.WriteString("# HELP " + name + " " + family.help + "\n")
+		b.WriteString("# TYPE " + name + " " + family.kind + "\n")
+		for _, sample := range family.samples {
+			b.WriteString(sample + "\n")
+		}
+	}
+	w.Write([]byte(b.String()))
+}
+
+func sampleLine(name string, value float64, labels []string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64))
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
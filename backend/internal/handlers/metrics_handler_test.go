@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k6clone/internal/engine"
+	"k6clone/internal/model"
+	"k6clone/internal/repository"
+	"k6clone/internal/service"
+)
+
+// scanlessRepository fails the test when the whole history is read.
+type scanlessRepository struct {
+	*repository.MemoryTestResultRepository
+	t *testing.T
+}
+
+func (r scanlessRepository) FindAll() []model.TestResult {
+	r.t.Error("FindAll called while serving /metrics")
+	return r.MemoryTestResultRepository.FindAll()
+}
+
+// waitForEnd polls a run until it is neither queued nor running.
+func waitForEnd(t *testing.T, tests *service.TestService, testID string) model.TestRun {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := tests.GetTest(testID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != model.Queued && run.Status != model.Running {
+			return run
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("test %s still running", testID)
+	return model.TestRun{}
+}
+
+func TestServerMetricsCountCompletedTests(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	scripts := repository.NewMemoryScriptRepository()
+	scripts.Save(&model.Script{ID: "script-1", Steps: []model.Step{{Method: "GET", URL: target.URL}}})
+	results := scanlessRepository{repository.NewMemoryTestResultRepository(), t}
+	tests := service.NewTestService(scripts, results, engine.NewLoadEngine(), t.TempDir())
+	handler := NewMetricsHandler(tests, service.NewScriptService(nil, scripts))
+
+	var short model.TestConfig
+	short.ScriptID = "script-1"
+	short.Executor = model.PerVUIterations
+	short.VUs = 1
+	short.Iterations = 2
+	for range 2 {
+		run, err := tests.RunTest(short)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run := waitForEnd(t, tests, run.TestID); run.Status != model.Finished {
+			t.Fatalf("run status = %s, want finished", run.Status)
+		}
+	}
+
+	long := short
+	long.Executor = model.ConstantVUs
+	long.Duration = 60
+	run, err := tests.RunTest(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tests.StopTest(run.TestID); err != nil {
+		t.Fatal(err)
+	}
+	if run := waitForEnd(t, tests, run.TestID); run.Status != model.Aborted {
+		t.Fatalf("stopped run status = %s, want aborted", run.Status)
+	}
+
+	// Imported k6 summaries are not runs of this server.
+	summary := `{"metrics":{"http_reqs":{"type":"counter","values":{"count":10,"rate":5}}}}`
+	if _, err := tests.ImportK6Summary(strings.NewReader(summary), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.GetMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`k6_server_tests_total{status="aborted"} 1`,
+		`k6_server_tests_total{status="finished"} 2`,
+		`k6_server_runs_active{status="running"} 0`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("/metrics lacks %q:\n%s", want, body)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"k6clone/internal/generator"
@@ -11,15 +12,18 @@ import (
 
 type ScriptHandler struct {
 	service *service.ScriptService
+	tests   *service.TestService
 	k6Gen   generator.K6JSGenerator
 }
 
 func NewScriptHandler(
 	s *service.ScriptService,
+	tests *service.TestService,
 	gen generator.K6JSGenerator,
 ) *ScriptHandler {
 	return &ScriptHandler{
 		service: s,
+		tests:   tests,
 		k6Gen:   gen,
 	}
 }
@@ -68,6 +72,59 @@ func (h *ScriptHandler) GetAllScripts(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(scripts)
 }
 
+// HandleScript serves /scripts/{id} and /scripts/{id}/baseline.
+func (h *ScriptHandler) HandleScript(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/scripts/"), "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] == "baseline" {
+		h.HandleBaseline(w, r, parts[0])
+		return
+	}
+	h.GetScriptByID(w, r)
+}
+
+// HandleBaseline gets, sets (PUT) or removes (DELETE) the baseline run new
+// runs of the script are checked for regressions against.
+func (h *ScriptHandler) HandleBaseline(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		baseline, err := h.tests.GetBaseline(id)
+		if err != nil {
+			writeBaselineError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(baseline)
+	case http.MethodPut:
+		var baseline model.Baseline
+		if err := json.NewDecoder(r.Body).Decode(&baseline); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+
+		baseline, err := h.tests.SetBaseline(id, baseline)
+		if err != nil {
+			writeBaselineError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(baseline)
+	case http.MethodDelete:
+		if err := h.tests.ClearBaseline(id); err != nil {
+			writeBaselineError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeBaselineError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrScriptNotFound) || errors.Is(err, service.ErrNoBaseline) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
 func (h *ScriptHandler) GetScriptByID(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/scripts/")
 	if id == "" {
@@ -100,8 +157,10 @@ func (h *ScriptHandler) GetK6Script(w http.ResponseWriter, r *http.Request) {
 	code, err := h.k6Gen.Generate(&generator.K6JSInput{
 		Script: script,
 		Config: model.TestConfig{
-			VUs:      10,
-			Duration: 30,
+			ExecutorConfig: model.ExecutorConfig{
+				VUs:      10,
+				Duration: 30,
+			},
 		},
 	})
 	if err != nil {
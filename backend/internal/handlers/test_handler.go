@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"k6clone/internal/model"
+	"k6clone/internal/report"
 	"k6clone/internal/service"
 )
 
@@ -24,11 +29,185 @@ func (h *TestHandler) RunTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.RunTest(config)
+	run, err := h.service.RunTest(config)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// ImportTest stores the k6 end-of-test summary in the body as a finished
+// test, attached to the script given by ?scriptId= if any.
+func (h *TestHandler) ImportTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.service.ImportK6Summary(r.Body, r.URL.Query().Get("scriptId"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(result)
 }
+
+// CompareTests compares the finished tests in the body's testIds, two or
+// more, against the first one.
+func (h *TestHandler) CompareTests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TestIDs []string `json:"testIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := h.service.CompareTests(req.TestIDs)
+	if errors.Is(err, service.ErrTestNotFound) || errors.Is(err, service.ErrTestRunning) {
+		writeTestError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// HandleTest serves /tests/{id}, /tests/{id}/stop, /tests/{id}/live and
+// /tests/{id}/export.
+func (h *TestHandler) HandleTest(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/tests/"), "/"), "/")
+	id := parts[0]
+	if id == "" {
+		http.Error(w, "test id required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		h.GetTest(w, id)
+	case len(parts) == 2 && parts[1] == "stop" && r.Method == http.MethodPost:
+		h.StopTest(w, id)
+	case len(parts) == 2 && parts[1] == "live" && r.Method == http.MethodGet:
+		h.StreamLive(w, r, id)
+	case len(parts) == 2 && parts[1] == "export" && r.Method == http.MethodGet:
+		h.ExportTest(w, r, id)
+	case len(parts) <= 2:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *TestHandler) GetTest(w http.ResponseWriter, id string) {
+	run, err := h.service.GetTest(id)
+	if err != nil {
+		writeTestError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+func (h *TestHandler) StopTest(w http.ResponseWriter, id string) {
+	run, err := h.service.StopTest(id)
+	if err != nil {
+		writeTestError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+// StreamLive sends the run's snapshots as Server-Sent Events until the run
+// ends or the client goes away.
+func (h *TestHandler) StreamLive(w http.ResponseWriter, r *http.Request, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	snapshots, unsubscribe, err := h.service.SubscribeLive(id)
+	if err != nil {
+		writeTestError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case snapshot, ok := <-snapshots:
+			if !ok {
+				fmt.Fprint(w, "event: end\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ExportTest sends a finished test's result as a download in the format given
+// by ?format= (json, csv, junit, html or k6).
+func (h *TestHandler) ExportTest(w http.ResponseWriter, r *http.Request, id string) {
+	exporter, err := report.NewExporter(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.GetResult(id)
+	if err != nil {
+		writeTestError(w, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := exporter.Export(&body, result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="test-`+id+`.`+exporter.Extension()+`"`)
+	w.Write(body.Bytes())
+}
+
+func writeTestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTestNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrTestNotRunning), errors.Is(err, service.ErrTestRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// Comparison lays out the values of several runs side by side, with each
+// value's change from the first run.
+type Comparison struct {
+	Tests   []ComparedTest     `json:"tests"`
+	Metrics []MetricComparison `json:"metrics"`
+}
+
+type ComparedTest struct {
+	TestID    string    `json:"testId"`
+	ScriptID  string    `json:"scriptId"`
+	Status    RunStatus `json:"status,omitempty"`
+	Passed    bool      `json:"passed"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// MetricComparison holds one stat of one metric, for the whole run (scope
+// "total") or one scenario ("scenario:<name>"), in the order of the compared
+// tests. A value is null when its run lacks the metric, and so is its delta.
+type MetricComparison struct {
+	Scope  string     `json:"scope"`
+	Metric string     `json:"metric"`
+	Stat   string     `json:"stat"`
+	Values []*float64 `json:"values"`
+	Deltas []*Delta   `json:"deltas"`
+}
+
+// Delta is a change from a reference value. Percent is null when the
+// reference is zero.
+type Delta struct {
+	Absolute float64  `json:"absolute"`
+	Percent  *float64 `json:"percent"`
+}
+
+// RegressionReport is the outcome of checking a run against its script's
+// baseline.
+type RegressionReport struct {
+	BaselineTestID string            `json:"baselineTestId"`
+	Regressed      bool              `json:"regressed"`
+	Checks         []RegressionCheck `json:"checks"`
+}
+
+type RegressionCheck struct {
+	Metric    string  `json:"metric"`
+	Stat      string  `json:"stat"`
+	Baseline  float64 `json:"baseline"`
+	Value     float64 `json:"value"`
+	Delta     Delta   `json:"delta"`
+	Allowed   float64 `json:"allowed"`
+	Regressed bool    `json:"regressed"`
+}
@@ -6,15 +6,96 @@ const (
 	HTTP StepType = "HTTP"
 )
 
+type CheckType string
+
+const (
+	CheckStatus       CheckType = "status"
+	CheckHeader       CheckType = "header"
+	CheckBodyContains CheckType = "bodyContains"
+	CheckBodyMatches  CheckType = "bodyMatches"
+	CheckJSONPath     CheckType = "jsonPath"
+	CheckMaxDuration  CheckType = "maxDuration"
+)
+
+type Check struct {
+	Name          string    `json:"name,omitempty"`
+	Type          CheckType `json:"type"`
+	StatusCodes   []int     `json:"statusCodes,omitempty"`
+	Header        string    `json:"header,omitempty"`
+	Path          string    `json:"path,omitempty"`
+	Value         string    `json:"value,omitempty"`
+	MaxDurationMs int64     `json:"maxDurationMs,omitempty"`
+}
+
+type MetricType string
+
+const (
+	MetricCounter MetricType = "counter"
+	MetricGauge   MetricType = "gauge"
+	MetricRate    MetricType = "rate"
+	MetricTrend   MetricType = "trend"
+)
+
+type MetricSource string
+
+const (
+	SourceValue    MetricSource = "value"
+	SourceJSONPath MetricSource = "jsonPath"
+	SourceCheck    MetricSource = "check"
+	SourceDuration MetricSource = "duration"
+	SourceStatus   MetricSource = "status"
+)
+
+// StepMetric adds a sample to a custom metric after every request of a step.
+// The sample is Value (1 when omitted), a number read from the JSON response
+// at Path, the outcome of the step's check named Check (1 or 0), the request
+// duration in milliseconds or the response status.
+type StepMetric struct {
+	Name   string            `json:"name"`
+	Type   MetricType        `json:"type"`
+	Source MetricSource      `json:"source,omitempty"`
+	Path   string            `json:"path,omitempty"`
+	Check  string            `json:"check,omitempty"`
+	Value  *float64          `json:"value,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
 type Step struct {
-	Type   StepType          `json:"type"`
-	Method string            `json:"method"`
-	URL    string            `json:"url"`
-	Header map[string]string `json:"header,omitempty"`
-	Body   string            `json:"body,omitempty"`
+	Name    string            `json:"name,omitempty"`
+	Type    StepType          `json:"type"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Header  map[string]string `json:"header,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Checks  []Check           `json:"checks,omitempty"`
+	Metrics []StepMetric      `json:"metrics,omitempty"`
 }
 
 type Script struct {
-	ID    string `json:"id"`
-	Steps []Step `json:"steps"`
+	ID       string    `json:"id"`
+	Steps    []Step    `json:"steps"`
+	Baseline *Baseline `json:"baseline,omitempty"`
+}
+
+// Baseline is the run a script's new runs are checked against. A new run
+// regresses when a tracked metric is worse than in the baseline by more than
+// its tolerance. The tracked metrics are the average, p(95) and p(99) request
+// duration, the failed request rate, the request rate and the check rate,
+// each allowed TolerancePercent (10 when unset), plus any in Tolerances.
+type Baseline struct {
+	TestID           string      `json:"testId"`
+	TolerancePercent float64     `json:"tolerancePercent,omitempty"`
+	Tolerances       []Tolerance `json:"tolerances,omitempty"`
+}
+
+// Tolerance is how much worse than in the baseline a metric's stat may get:
+// Percent of the baseline value or Absolute, in the stat's unit, whichever is
+// larger. Rates of requests, iterations and checks are worse when lower, any
+// other stat when higher.
+type Tolerance struct {
+	Metric   string  `json:"metric"`
+	Stat     string  `json:"stat"`
+	Percent  float64 `json:"percent,omitempty"`
+	Absolute float64 `json:"absolute,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type Snapshot struct {
+	Time              time.Time `json:"time"`
+	ElapsedSec        float64   `json:"elapsedSec"`
+	ActiveVUs         int       `json:"activeVUs"`
+	TotalRequests     int       `json:"totalRequests"`
+	Iterations        int       `json:"iterations"`
+	DroppedIterations int       `json:"droppedIterations"`
+	DataSent          int       `json:"dataSent"`
+	DataReceived      int       `json:"dataReceived"`
+	RPS               float64   `json:"rps"`
+	ErrorRate         float64   `json:"errorRate"`
+	AvgLatencyMs      int64     `json:"avgLatencyMs"`
+	P50LatencyMs      int64     `json:"p50LatencyMs"`
+	P90LatencyMs      int64     `json:"p90LatencyMs"`
+	P95LatencyMs      int64     `json:"p95LatencyMs"`
+	P99LatencyMs      int64     `json:"p99LatencyMs"`
+	P95ResponseTimeMs int64     `json:"p95ResponseTimeMs"`
+	P99ResponseTimeMs int64     `json:"p99ResponseTimeMs"`
+}
@@ -9,26 +9,225 @@ const (
 	Load   TestType = "load"
 	Stress TestType = "stress"
 	Spike  TestType = "spike"
+	Soak   TestType = "soak"
 )
 
+type Executor string
+
+const (
+	ConstantVUs         Executor = "constant-vus"
+	RampingVUs          Executor = "ramping-vus"
+	ConstantArrivalRate Executor = "constant-arrival-rate"
+	RampingArrivalRate  Executor = "ramping-arrival-rate"
+	PerVUIterations     Executor = "per-vu-iterations"
+	SharedIterations    Executor = "shared-iterations"
+)
+
+type RunStatus string
+
+const (
+	Queued   RunStatus = "queued"
+	Running  RunStatus = "running"
+	Finished RunStatus = "finished"
+	Aborted  RunStatus = "aborted"
+)
+
+type Stage struct {
+	Duration int `json:"duration"`
+	Target   int `json:"target"`
+}
+
+type Threshold struct {
+	Metric         string  `json:"metric"`
+	Condition      string  `json:"condition,omitempty"`
+	Aggregation    string  `json:"aggregation,omitempty"`
+	Operator       string  `json:"operator,omitempty"`
+	Value          float64 `json:"value"`
+	AbortOnFail    bool    `json:"abortOnFail,omitempty"`
+	DelayAbortEval int     `json:"delayAbortEval,omitempty"`
+}
+
+type ThresholdResult struct {
+	Threshold
+	Actual float64 `json:"actual"`
+	Passed bool    `json:"passed"`
+}
+
+type ExecutorConfig struct {
+	Executor        Executor `json:"executor,omitempty"`
+	VUs             int      `json:"vus"`
+	Duration        int      `json:"duration"`
+	Stages          []Stage  `json:"stages,omitempty"`
+	Rate            int      `json:"rate,omitempty"`
+	StartRate       int      `json:"startRate,omitempty"`
+	TimeUnit        string   `json:"timeUnit,omitempty"`
+	PreAllocatedVUs int      `json:"preAllocatedVUs,omitempty"`
+	MaxVUs          int      `json:"maxVUs,omitempty"`
+	Iterations      int      `json:"iterations,omitempty"`
+	MaxDuration     int      `json:"maxDuration,omitempty"`
+}
+
+type OutputType string
+
+const (
+	OutputJSON   OutputType = "json"
+	OutputCSV    OutputType = "csv"
+	OutputInflux OutputType = "influxdb"
+	OutputStatsD OutputType = "statsd"
+	OutputOTLP   OutputType = "otlp"
+)
+
+// OutputConfig selects a sink for a run's raw samples. JSON and CSV create
+// the new file named Path in the server's outputs directory; InfluxDB posts
+// to an http(s) write URL or sends to a udp://host:port URL; StatsD sends to
+// Address, prefixing names with Prefix. OTLP posts metrics to URL's
+// /v1/metrics and, with Traces, a span per request to /v1/traces, sending
+// Headers with each export. Runs and results keep Headers and URL
+// credentials redacted.
+type OutputConfig struct {
+	Type    OutputType        `json:"type"`
+	Path    string            `json:"path,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Address string            `json:"address,omitempty"`
+	Prefix  string            `json:"prefix,omitempty"`
+	Traces  bool              `json:"traces,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type Scenario struct {
+	ExecutorConfig
+	ScriptID  string            `json:"scriptId,omitempty"`
+	StartTime int               `json:"startTime,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
 type TestConfig struct {
 	ScriptID string   `json:"scriptId"`
 	Type     TestType `json:"type"`
-	VUs      int      `json:"vus"`
-	Duration int      `json:"duration"` 
+	ExecutorConfig
+	Scenarios  map[string]Scenario `json:"scenarios,omitempty"`
+	Thresholds []Threshold         `json:"thresholds,omitempty"`
+	Tags       map[string]string   `json:"tags,omitempty"`
+	Outputs    []OutputConfig      `json:"outputs,omitempty"`
+
+	DiscardResponseBodies bool `json:"discardResponseBodies,omitempty"`
+}
+
+type CheckResult struct {
+	Name   string `json:"name"`
+	Passes int    `json:"passes"`
+	Fails  int    `json:"fails"`
+}
+
+// TrendStats summarizes a duration metric in milliseconds.
+type TrendStats struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg"`
+	Min   float64 `json:"min"`
+	Med   float64 `json:"med"`
+	Max   float64 `json:"max"`
+	P90   float64 `json:"p(90)"`
+	P95   float64 `json:"p(95)"`
+	P99   float64 `json:"p(99)"`
+	P999  float64 `json:"p(99.9)"`
+}
+
+type RequestStats struct {
+	Requests    int         `json:"requests"`
+	Failures    int         `json:"failures"`
+	Latency     TrendStats  `json:"latency"`
+	StatusCodes map[int]int `json:"statusCodes"`
+}
+
+type StepResult struct {
+	ScriptID string `json:"scriptId"`
+	Index    int    `json:"index"`
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	RequestStats
+}
+
+type EndpointResult struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	RequestStats
+}
+
+type ErrorResult struct {
+	Category  string    `json:"category"`
+	Count     int       `json:"count"`
+	Sample    string    `json:"sample"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+type MetricResult struct {
+	Name   string             `json:"name"`
+	Type   MetricType         `json:"type"`
+	Tags   map[string]string  `json:"tags,omitempty"`
+	Values map[string]float64 `json:"values"`
+}
+
+// Summary aggregates a run or scenario. Latency is the service time of each
+// request; ResponseTime adds the time an arrival-rate iteration waited for a
+// free VU, so queueing is not hidden when the system under test falls behind.
+type Summary struct {
+	TotalRequests     int                   `json:"totalRequests"`
+	Success           int                   `json:"success"`
+	Failure           int                   `json:"failure"`
+	AvgLatencyMs      int64                 `json:"avgLatencyMs"`
+	P90LatencyMs      int64                 `json:"p90LatencyMs"`
+	P95LatencyMs      int64                 `json:"p95LatencyMs"`
+	P99LatencyMs      int64                 `json:"p99LatencyMs"`
+	Latency           TrendStats            `json:"latency"`
+	ResponseTime      TrendStats            `json:"responseTime"`
+	RPS               float64               `json:"rps"`
+	Iterations        int                   `json:"iterations"`
+	DroppedIterations int                   `json:"droppedIterations"`
+	DataSent          int                   `json:"dataSent"`
+	DataReceived      int                   `json:"dataReceived"`
+	DataSentRate      float64               `json:"dataSentRate"`
+	DataReceivedRate  float64               `json:"dataReceivedRate"`
+	Checks            []CheckResult         `json:"checks,omitempty"`
+	StatusCodes       map[int]int           `json:"statusCodes,omitempty"`
+	Errors            []ErrorResult         `json:"errors,omitempty"`
+	Timings           map[string]TrendStats `json:"timings,omitempty"`
+	Steps             []StepResult          `json:"steps,omitempty"`
+	Endpoints         []EndpointResult      `json:"endpoints,omitempty"`
+	Metrics           []MetricResult        `json:"metrics,omitempty"`
+}
+
+type ScenarioResult struct {
+	ScriptID string            `json:"scriptId"`
+	Executor Executor          `json:"executor"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Summary
 }
 
 type TestResult struct {
-	TestID        string    `json:"testId"`
-	ScriptID      string    `json:"scriptId"`
-	TotalRequests int       `json:"totalRequests"`
-	Success       int       `json:"success"`
-	Failure       int       `json:"failure"`
-	AvgLatencyMs  int64     `json:"avgLatencyMs"`
-	P90LatencyMs  int64     `json:"p90LatencyMs"`
-	P95LatencyMs  int64     `json:"p95LatencyMs"`
-	P99LatencyMs  int64     `json:"p99LatencyMs"`
-	RPS           float64   `json:"rps"`
-	Iterations    int       `json:"iterations"`
-	StartedAt     time.Time `json:"startedAt"`
-}
\ No newline at end of file
+	TestID   string     `json:"testId"`
+	ScriptID string     `json:"scriptId"`
+	Status   RunStatus  `json:"status,omitempty"`
+	Config   TestConfig `json:"config"`
+	Summary
+	Scenarios    map[string]ScenarioResult `json:"scenarios,omitempty"`
+	Thresholds   []ThresholdResult         `json:"thresholds,omitempty"`
+	TimeSeries   []Snapshot                `json:"timeSeries,omitempty"`
+	DurationSec  float64                   `json:"durationSec,omitempty"`
+	Passed       bool                      `json:"passed"`
+	AbortReason  string                    `json:"abortReason,omitempty"`
+	OutputErrors []string                  `json:"outputErrors,omitempty"`
+	Regression   *RegressionReport         `json:"regression,omitempty"`
+	StartedAt    time.Time                 `json:"startedAt"`
+}
+
+type TestRun struct {
+	TestID     string      `json:"testId"`
+	ScriptID   string      `json:"scriptId"`
+	Status     RunStatus   `json:"status"`
+	Config     TestConfig  `json:"config"`
+	QueuedAt   time.Time   `json:"queuedAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+	Result     *TestResult `json:"result,omitempty"`
+}
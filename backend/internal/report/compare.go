@@ -0,0 +1,174 @@
+package report
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"k6clone/internal/model"
+)
+
+// defaultTolerancePercent is how much worse than its baseline a run may get
+// when the baseline sets no tolerance.
+const defaultTolerancePercent = 10
+
+// Compare lays out every value of the results side by side, with deltas
+// against the first result. Values only some results have are included, as
+// null for the others.
+func Compare(results []model.TestResult) model.Comparison {
+	comparison := model.Comparison{Tests: []model.ComparedTest{}, Metrics: []model.MetricComparison{}}
+
+	type key struct{ scope, metric, stat string }
+	index := make(map[key]int)
+	for i, result := range results {
+		comparison.Tests = append(comparison.Tests, model.ComparedTest{
+			TestID:    result.TestID,
+			ScriptID:  result.ScriptID,
+			Status:    result.Status,
+			Passed:    result.Passed,
+			StartedAt: result.StartedAt,
+		})
+
+		for _, v := range resultValues(result) {
+			k := key{v.scope, v.metric, v.stat}
+			j, ok := index[k]
+			if !ok {
+				j = len(comparison.Metrics)
+				index[k] = j
+				comparison.Metrics = append(comparison.Metrics, model.MetricComparison{
+					Scope:  v.scope,
+					Metric: v.metric,
+					Stat:   v.stat,
+					Values: make([]*float64, len(results)),
+					Deltas: make([]*model.Delta, len(results)),
+				})
+			}
+			comparison.Metrics[j].Values[i] = &v.value
+		}
+	}
+
+	for _, metric := range comparison.Metrics {
+		reference := metric.Values[0]
+		if reference == nil {
+			continue
+		}
+		for i, value := range metric.Values {
+			if value != nil {
+				d := delta(*reference, *value)
+				metric.Deltas[i] = &d
+			}
+		}
+	}
+
+	return comparison
+}
+
+type scopedValue struct {
+	scope string
+	metricValue
+}
+
+// resultValues lists the values of a run, then of each of its scenarios.
+func resultValues(result model.TestResult) []scopedValue {
+	var values []scopedValue
+	for _, v := range summaryValues(result.Summary) {
+		values = append(values, scopedValue{"total", v})
+	}
+	for _, name := range slices.Sorted(maps.Keys(result.Scenarios)) {
+		for _, v := range summaryValues(result.Scenarios[name].Summary) {
+			values = append(values, scopedValue{"scenario:" + name, v})
+		}
+	}
+	return values
+}
+
+func delta(reference, value float64) model.Delta {
+	d := model.Delta{Absolute: value - reference}
+	if reference != 0 {
+		percent := d.Absolute / math.Abs(reference) * 100
+		d.Percent = &percent
+	}
+	return d
+}
+
+// CheckRegression checks a run's totals against the run of its baseline.
+// Tracked metrics either run lacks are skipped.
+func CheckRegression(baseline model.Baseline, reference, result model.TestResult) *model.RegressionReport {
+	report := &model.RegressionReport{BaselineTestID: baseline.TestID, Checks: []model.RegressionCheck{}}
+
+	referenceValues := make(map[[2]string]float64)
+	for _, v := range summaryValues(reference.Summary) {
+		referenceValues[[2]string{v.metric, v.stat}] = v.value
+	}
+	values := make(map[[2]string]float64)
+	for _, v := range summaryValues(result.Summary) {
+		values[[2]string{v.metric, v.stat}] = v.value
+	}
+
+	for _, tolerance := range tolerances(baseline) {
+		k := [2]string{tolerance.Metric, tolerance.Stat}
+		base, ok := referenceValues[k]
+		if !ok {
+			continue
+		}
+		value, ok := values[k]
+		if !ok {
+			continue
+		}
+
+		worse := value - base
+		if lowerIsWorse(tolerance.Metric, tolerance.Stat) {
+			worse = -worse
+		}
+		allowed := max(math.Abs(base)*tolerance.Percent/100, tolerance.Absolute)
+
+		check := model.RegressionCheck{
+			Metric:    tolerance.Metric,
+			Stat:      tolerance.Stat,
+			Baseline:  base,
+			Value:     value,
+			Delta:     delta(base, value),
+			Allowed:   allowed,
+			Regressed: worse > allowed,
+		}
+		report.Checks = append(report.Checks, check)
+		report.Regressed = report.Regressed || check.Regressed
+	}
+
+	return report
+}
+
+// tolerances are the default tracked metrics at the baseline's tolerance,
+// with the baseline's own tolerances replacing or adding to them.
+func tolerances(baseline model.Baseline) []model.Tolerance {
+	percent := baseline.TolerancePercent
+	if percent == 0 {
+		percent = defaultTolerancePercent
+	}
+
+	list := []model.Tolerance{
+		{Metric: "http_req_duration", Stat: "avg", Percent: percent},
+		{Metric: "http_req_duration", Stat: "p(95)", Percent: percent},
+		{Metric: "http_req_duration", Stat: "p(99)", Percent: percent},
+		{Metric: "http_req_failed", Stat: "rate", Percent: percent, Absolute: 0.01},
+		{Metric: "http_reqs", Stat: "rate", Percent: percent},
+		{Metric: "checks", Stat: "rate", Percent: percent, Absolute: 0.01},
+	}
+	for _, tolerance := range baseline.Tolerances {
+		i := slices.IndexFunc(list, func(t model.Tolerance) bool {
+			return t.Metric == tolerance.Metric && t.Stat == tolerance.Stat
+		})
+		if i < 0 {
+			list = append(list, tolerance)
+		} else {
+			list[i] = tolerance
+		}
+	}
+	return list
+}
+
+func lowerIsWorse(metric, stat string) bool {
+	name, _, _ := strings.Cut(metric, "{")
+	return stat == "rate" && (name == "http_reqs" || name == "iterations" || name == "checks")
+}
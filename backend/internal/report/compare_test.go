@@ -0,0 +1,191 @@
+package report
+
+import (
+	"math"
+	"testing"
+
+	"k6clone/internal/model"
+)
+
+// summaryWith returns a summary whose stat of metric is value. Rates of
+// failed requests and checks are spread over 1000 requests and checks.
+func summaryWith(metric, stat string, value float64) model.Summary {
+	s := model.Summary{TotalRequests: 1000}
+	switch metric + " " + stat {
+	case "http_req_duration avg":
+		s.Latency.Avg = value
+	case "http_req_duration p(95)":
+		s.Latency.P95 = value
+	case "http_reqs rate":
+		s.RPS = value
+	case "http_req_failed rate":
+		s.Failure = int(math.Round(value * 1000))
+	case "checks rate", "checks{check:status is 200} rate":
+		passes := int(math.Round(value * 1000))
+		s.Checks = []model.CheckResult{{Name: "status is 200", Passes: passes, Fails: 1000 - passes}}
+	default:
+		panic("summaryWith: unsupported " + metric + " " + stat)
+	}
+	return s
+}
+
+func TestCheckRegression(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseline      model.Baseline
+		metric, stat  string
+		base, value   float64
+		wantAllowed   float64
+		wantRegressed bool
+	}{
+		{"duration within default percent", model.Baseline{}, "http_req_duration", "avg", 100, 109, 10, false},
+		{"duration past default percent", model.Baseline{}, "http_req_duration", "avg", 100, 111, 10, true},
+		{"duration improved", model.Baseline{}, "http_req_duration", "avg", 100, 40, 10, false},
+		{"duration past baseline percent", model.Baseline{TolerancePercent: 25}, "http_req_duration", "p(95)", 200, 260, 50, true},
+		{"duration within baseline percent", model.Baseline{TolerancePercent: 25}, "http_req_duration", "p(95)", 200, 240, 50, false},
+
+		{"request rate dropped past percent", model.Baseline{}, "http_reqs", "rate", 100, 85, 10, true},
+		{"request rate dropped within percent", model.Baseline{}, "http_reqs", "rate", 100, 95, 10, false},
+		{"request rate rose", model.Baseline{}, "http_reqs", "rate", 100, 300, 10, false},
+		{"check rate dropped past absolute", model.Baseline{}, "checks", "rate", 0.05, 0.03, 0.01, true},
+		{"check rate rose", model.Baseline{}, "checks", "rate", 0.5, 1, 0.05, false},
+		{
+			"tagged check rate is worse when lower",
+			model.Baseline{Tolerances: []model.Tolerance{{Metric: "checks{check:status is 200}", Stat: "rate", Absolute: 0.02}}},
+			"checks{check:status is 200}", "rate", 0.99, 0.96, 0.02, true,
+		},
+
+		{"failed rate within absolute of zero", model.Baseline{}, "http_req_failed", "rate", 0, 0.005, 0.01, false},
+		{"failed rate past absolute of zero", model.Baseline{}, "http_req_failed", "rate", 0, 0.02, 0.01, true},
+		{"failed rate fell", model.Baseline{}, "http_req_failed", "rate", 0.5, 0.1, 0.05, false},
+
+		{
+			"absolute wins over smaller percent",
+			model.Baseline{Tolerances: []model.Tolerance{{Metric: "http_req_duration", Stat: "avg", Percent: 5, Absolute: 20}}},
+			"http_req_duration", "avg", 100, 118, 20, false,
+		},
+		{
+			"absolute exceeded",
+			model.Baseline{Tolerances: []model.Tolerance{{Metric: "http_req_duration", Stat: "avg", Percent: 5, Absolute: 20}}},
+			"http_req_duration", "avg", 100, 121, 20, true,
+		},
+		{
+			"percent wins over smaller absolute",
+			model.Baseline{Tolerances: []model.Tolerance{{Metric: "http_reqs", Stat: "rate", Percent: 50, Absolute: 1}}},
+			"http_reqs", "rate", 100, 60, 50, false,
+		},
+		{
+			"percent exceeded",
+			model.Baseline{Tolerances: []model.Tolerance{{Metric: "http_reqs", Stat: "rate", Percent: 50, Absolute: 1}}},
+			"http_reqs", "rate", 100, 49, 50, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference := model.TestResult{Summary: summaryWith(tt.metric, tt.stat, tt.base)}
+			result := model.TestResult{Summary: summaryWith(tt.metric, tt.stat, tt.value)}
+			tt.baseline.TestID = "baseline"
+
+			report := CheckRegression(tt.baseline, reference, result)
+			if report.BaselineTestID != "baseline" {
+				t.Errorf("baseline test id = %q", report.BaselineTestID)
+			}
+
+			var found *model.RegressionCheck
+			for i, check := range report.Checks {
+				if check.Metric == tt.metric && check.Stat == tt.stat {
+					found = &report.Checks[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("no check of %s %s in %+v", tt.metric, tt.stat, report.Checks)
+			}
+			if math.Abs(found.Allowed-tt.wantAllowed) > 1e-9 {
+				t.Errorf("allowed = %v, want %v", found.Allowed, tt.wantAllowed)
+			}
+			if found.Regressed != tt.wantRegressed {
+				t.Errorf("regressed = %v, want %v (%v -> %v)", found.Regressed, tt.wantRegressed, tt.base, tt.value)
+			}
+			if report.Regressed != tt.wantRegressed {
+				t.Errorf("report regressed = %v, want %v", report.Regressed, tt.wantRegressed)
+			}
+		})
+	}
+}
+
+func TestCheckRegressionSkipsMissingMetrics(t *testing.T) {
+	baseline := model.Baseline{TestID: "baseline", Tolerances: []model.Tolerance{{Metric: "orders", Stat: "count", Percent: 1}}}
+	reference := model.TestResult{Summary: model.Summary{Latency: model.TrendStats{Avg: 100}}}
+	result := model.TestResult{Summary: model.Summary{Latency: model.TrendStats{Avg: 100}}}
+
+	report := CheckRegression(baseline, reference, result)
+	for _, check := range report.Checks {
+		if check.Metric == "orders" || check.Metric == "checks" {
+			t.Errorf("check of %s %s, want metrics the runs lack skipped", check.Metric, check.Stat)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	results := []model.TestResult{
+		{TestID: "a", Summary: model.Summary{Latency: model.TrendStats{Avg: 200}, RPS: 0}},
+		{
+			TestID:  "b",
+			Summary: model.Summary{Latency: model.TrendStats{Avg: 150}, RPS: 10},
+			Scenarios: map[string]model.ScenarioResult{
+				"browse": {Summary: model.Summary{Latency: model.TrendStats{Avg: 80}}},
+			},
+		},
+		{TestID: "c", Summary: model.Summary{Latency: model.TrendStats{Avg: 300}, RPS: 20}},
+	}
+	comparison := Compare(results)
+
+	if len(comparison.Tests) != 3 || comparison.Tests[1].TestID != "b" {
+		t.Fatalf("tests = %+v, want a, b, c in order", comparison.Tests)
+	}
+
+	find := func(scope, metric, stat string) model.MetricComparison {
+		t.Helper()
+		for _, m := range comparison.Metrics {
+			if m.Scope == scope && m.Metric == metric && m.Stat == stat {
+				return m
+			}
+		}
+		t.Fatalf("no %s %s %s in the comparison", scope, metric, stat)
+		return model.MetricComparison{}
+	}
+
+	avg := find("total", "http_req_duration", "avg")
+	for i, want := range []struct{ absolute, percent float64 }{{0, 0}, {-50, -25}, {100, 50}} {
+		d := avg.Deltas[i]
+		if d == nil || d.Absolute != want.absolute || d.Percent == nil || *d.Percent != want.percent {
+			t.Errorf("avg delta %d = %+v, want %v (%v%%)", i, d, want.absolute, want.percent)
+		}
+	}
+
+	// A zero reference has no percent change.
+	rate := find("total", "http_reqs", "rate")
+	if d := rate.Deltas[2]; d == nil || d.Absolute != 20 || d.Percent != nil {
+		t.Errorf("rate delta = %+v, want +20 without a percent", d)
+	}
+
+	// Values only some runs have are null elsewhere, and without a
+	// reference value nothing has a delta.
+	scenario := find("scenario:browse", "http_req_duration", "avg")
+	if scenario.Values[0] != nil || scenario.Values[1] == nil || *scenario.Values[1] != 80 || scenario.Values[2] != nil {
+		t.Errorf("scenario values = %v, want only b's", scenario.Values)
+	}
+	for i, d := range scenario.Deltas {
+		if d != nil {
+			t.Errorf("scenario delta %d = %+v, want null without a reference", i, d)
+		}
+	}
+}
+
+func TestDeltaPercentOfNegativeReference(t *testing.T) {
+	d := delta(-10, -5)
+	if d.Absolute != 5 || d.Percent == nil || *d.Percent != 50 {
+		t.Errorf("delta(-10, -5) = %+v, want +5 (+50%%)", d)
+	}
+}
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+// csvExporter writes two tables separated by a blank line: the summary, one
+// row per metric value of the run and of each scenario, then the per-second
+// time series.
+type csvExporter struct{}
+
+func (csvExporter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (csvExporter) Extension() string { return "csv" }
+
+func (csvExporter) Export(w io.Writer, result model.TestResult) error {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"scope", "metric", "stat", "value"})
+	for _, row := range summaryRows(result.Summary) {
+		writer.Write(append([]string{"total"}, row...))
+	}
+	for _, name := range slices.Sorted(maps.Keys(result.Scenarios)) {
+		for _, row := range summaryRows(result.Scenarios[name].Summary) {
+			writer.Write(append([]string{"scenario:" + name}, row...))
+		}
+	}
+
+	writer.Write(nil)
+	writer.Write([]string{
+		"time", "elapsed_sec", "active_vus", "total_requests", "iterations", "dropped_iterations",
+		"data_sent", "data_received", "rps", "error_rate", "avg_latency_ms", "p50_latency_ms",
+		"p90_latency_ms", "p95_latency_ms", "p99_latency_ms", "p95_response_time_ms", "p99_response_time_ms",
+	})
+	for _, s := range result.TimeSeries {
+		writer.Write([]string{
+			s.Time.Format(time.RFC3339Nano),
+			formatFloat(s.ElapsedSec),
+			strconv.Itoa(s.ActiveVUs),
+			strconv.Itoa(s.TotalRequests),
+			strconv.Itoa(s.Iterations),
+			strconv.Itoa(s.DroppedIterations),
+			strconv.Itoa(s.DataSent),
+			strconv.Itoa(s.DataReceived),
+			formatFloat(s.RPS),
+			formatFloat(s.ErrorRate),
+			strconv.FormatInt(s.AvgLatencyMs, 10),
+			strconv.FormatInt(s.P50LatencyMs, 10),
+			strconv.FormatInt(s.P90LatencyMs, 10),
+			strconv.FormatInt(s.P95LatencyMs, 10),
+			strconv.FormatInt(s.P99LatencyMs, 10),
+			strconv.FormatInt(s.P95ResponseTimeMs, 10),
+			strconv.FormatInt(s.P99ResponseTimeMs, 10),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// summaryRows lists the values of a summary as metric, stat and value.
+func summaryRows(s model.Summary) [][]string {
+	var rows [][]string
+	for _, v := range summaryValues(s) {
+		rows = append(rows, []string{v.metric, v.stat, formatFloat(v.value)})
+	}
+	return rows
+}
+
+type metricValue struct {
+	metric string
+	stat   string
+	value  float64
+}
+
+// summaryValues lists every value of a summary, naming metrics as k6 does and
+// tagged series in k6's sub-metric syntax.
+func summaryValues(s model.Summary) []metricValue {
+	var values []metricValue
+	add := func(metric, stat string, value float64) {
+		values = append(values, metricValue{metric, stat, value})
+	}
+	trend := func(metric string, t model.TrendStats) {
+		add(metric, "avg", t.Avg)
+		add(metric, "min", t.Min)
+		add(metric, "med", t.Med)
+		add(metric, "max", t.Max)
+		add(metric, "p(90)", t.P90)
+		add(metric, "p(95)", t.P95)
+		add(metric, "p(99)", t.P99)
+		add(metric, "p(99.9)", t.P999)
+	}
+
+	add("http_reqs", "count", float64(s.TotalRequests))
+	add("http_reqs", "rate", s.RPS)
+	add("http_req_failed", "count", float64(s.Failure))
+	add("http_req_failed", "rate", ratio(s.Failure, s.TotalRequests))
+	add("iterations", "count", float64(s.Iterations))
+	add("dropped_iterations", "count", float64(s.DroppedIterations))
+	add("data_sent", "count", float64(s.DataSent))
+	add("data_sent", "rate", s.DataSentRate)
+	add("data_received", "count", float64(s.DataReceived))
+	add("data_received", "rate", s.DataReceivedRate)
+	trend("http_req_duration", s.Latency)
+	trend("http_req_response_time", s.ResponseTime)
+	for _, name := range slices.Sorted(maps.Keys(s.Timings)) {
+		trend(name, s.Timings[name])
+	}
+
+	if len(s.Checks) > 0 {
+		passes, fails := 0, 0
+		for _, check := range s.Checks {
+			passes += check.Passes
+			fails += check.Fails
+		}
+		add("checks", "passes", float64(passes))
+		add("checks", "fails", float64(fails))
+		add("checks", "rate", ratio(passes, passes+fails))
+	}
+	for _, check := range s.Checks {
+		metric := "checks{check:" + check.Name + "}"
+		add(metric, "passes", float64(check.Passes))
+		add(metric, "fails", float64(check.Fails))
+		add(metric, "rate", ratio(check.Passes, check.Passes+check.Fails))
+	}
+
+	for _, code := range slices.Sorted(maps.Keys(s.StatusCodes)) {
+		add("http_reqs{status:"+strconv.Itoa(code)+"}", "count", float64(s.StatusCodes[code]))
+	}
+
+	for _, metric := range s.Metrics {
+		name := metricKey(metric.Name, metric.Tags)
+		for _, stat := range slices.Sorted(maps.Keys(metric.Values)) {
+			add(name, stat, metric.Values[stat])
+		}
+	}
+
+	return values
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,311 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+const (
+	chartWidth   = 640
+	chartHeight  = 220
+	chartPadding = 40
+	chartTicks   = 4
+)
+
+// htmlExporter writes a self-contained HTML report: inline styles and SVG
+// charts, no scripts or external assets.
+type htmlExporter struct{}
+
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+type chartTick struct {
+	Y     float64
+	Label string
+}
+
+type chart struct {
+	Title  string
+	Series []chartSeries
+	Ticks  []chartTick
+	XLabel string
+}
+
+type htmlReport struct {
+	model.TestResult
+	Duration  string
+	ErrorRate float64
+	Charts    []chart
+	Scenarios []string
+}
+
+func (htmlExporter) ContentType() string { return "text/html; charset=utf-8" }
+
+func (htmlExporter) Extension() string { return "html" }
+
+func (htmlExporter) Export(w io.Writer, result model.TestResult) error {
+	report := htmlReport{
+		TestResult: result,
+		Duration:   (time.Duration(runSeconds(result)*1000) * time.Millisecond).String(),
+		ErrorRate:  ratio(result.Failure, result.TotalRequests) * 100,
+		Scenarios:  slices.Sorted(maps.Keys(result.Scenarios)),
+	}
+
+	series := result.TimeSeries
+	if len(series) > 1 {
+		value := func(f func(model.Snapshot) float64) []float64 {
+			values := make([]float64, len(series))
+			for i, s := range series {
+				values[i] = f(s)
+			}
+			return values
+		}
+
+		report.Charts = []chart{
+			newChart("Latency (ms)", series, []namedValues{
+				{"p50", "#16a34a", value(func(s model.Snapshot) float64 { return float64(s.P50LatencyMs) })},
+				{"p95", "#d97706", value(func(s model.Snapshot) float64 { return float64(s.P95LatencyMs) })},
+				{"p99", "#dc2626", value(func(s model.Snapshot) float64 { return float64(s.P99LatencyMs) })},
+				{"p99 response", "#9333ea", value(func(s model.Snapshot) float64 { return float64(s.P99ResponseTimeMs) })},
+			}),
+			newChart("Requests per second", series, []namedValues{
+				{"rps", "#2563eb", value(func(s model.Snapshot) float64 { return s.RPS })},
+			}),
+			newChart("Active VUs", series, []namedValues{
+				{"vus", "#0891b2", value(func(s model.Snapshot) float64 { return float64(s.ActiveVUs) })},
+			}),
+			newChart("Error rate (%)", series, []namedValues{
+				{"errors", "#dc2626", value(func(s model.Snapshot) float64 { return s.ErrorRate * 100 })},
+			}),
+		}
+	}
+
+	return htmlTemplate.Execute(w, report)
+}
+
+type namedValues struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// newChart scales the series into the chart's plot area, with elapsed
+// seconds on the x axis and a y axis starting at zero.
+func newChart(title string, snapshots []model.Snapshot, lines []namedValues) chart {
+	maxX := snapshots[len(snapshots)-1].ElapsedSec
+	maxY := 0.0
+	for _, line := range lines {
+		maxY = max(maxY, slices.Max(line.values))
+	}
+	if maxX <= 0 {
+		maxX = 1
+	}
+	if maxY <= 0 {
+		maxY = 1
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+
+	c := chart{Title: title, XLabel: fmt.Sprintf("%.0fs", maxX)}
+	for _, line := range lines {
+		points := make([]string, len(line.values))
+		for i, v := range line.values {
+			x := chartPadding + snapshots[i].ElapsedSec/maxX*plotWidth
+			y := chartHeight - chartPadding - v/maxY*plotHeight
+			points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+		}
+		c.Series = append(c.Series, chartSeries{Name: line.name, Color: line.color, Points: strings.Join(points, " ")})
+	}
+	for i := 0; i <= chartTicks; i++ {
+		v := maxY * float64(i) / chartTicks
+		c.Ticks = append(c.Ticks, chartTick{
+			Y:     chartHeight - chartPadding - v/maxY*plotHeight,
+			Label: fmt.Sprintf("%.4g", v),
+		})
+	}
+	return c
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":  func(v float64) string { return fmt.Sprintf("%.2f ms", v) },
+	"pct": func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"num": func(v float64) string { return fmt.Sprintf("%.4g", v) },
+	"bytes": func(n int) string {
+		const unit = 1024
+		if n < unit {
+			return fmt.Sprintf("%d B", n)
+		}
+		value, exp := float64(n)/unit, 0
+		for value >= unit && exp < 3 {
+			value /= unit
+			exp++
+		}
+		return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+	},
+	"time":   func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05 UTC") },
+	"width":  func() int { return chartWidth },
+	"height": func() int { return chartHeight },
+	"left":   func() int { return chartPadding },
+	"right":  func() int { return chartWidth - chartPadding },
+	"bottom": func() int { return chartHeight - chartPadding },
+	"legend": func(i int) int { return chartPadding + i*110 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Test report {{.TestID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 32px; color: #0f172a; background: #f8fafc; }
+h1 { margin-bottom: 4px; }
+h2 { margin-top: 32px; border-bottom: 1px solid #cbd5e1; padding-bottom: 4px; }
+.meta { color: #475569; }
+.badge { display: inline-block; padding: 2px 10px; border-radius: 999px; color: #fff; font-weight: 600; }
+.pass { background: #16a34a; }
+.fail { background: #dc2626; }
+.cards { display: flex; flex-wrap: wrap; gap: 12px; margin-top: 16px; }
+.card { background: #fff; border: 1px solid #e2e8f0; border-radius: 8px; padding: 12px 16px; min-width: 140px; }
+.card .label { color: #64748b; font-size: 12px; text-transform: uppercase; }
+.card .value { font-size: 22px; font-weight: 600; }
+.charts { display: flex; flex-wrap: wrap; gap: 16px; }
+.chart { background: #fff; border: 1px solid #e2e8f0; border-radius: 8px; padding: 8px; }
+.chart h3 { margin: 4px 8px; font-size: 14px; }
+table { border-collapse: collapse; background: #fff; margin-top: 8px; }
+th, td { border: 1px solid #e2e8f0; padding: 6px 10px; text-align: left; font-size: 14px; }
+th { background: #f1f5f9; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+</style>
+</head>
+<body>
+<h1>Test report</h1>
+<div class="meta">
+  Test {{.TestID}} · script {{.ScriptID}} · started {{time .StartedAt}} · ran {{.Duration}}
+  {{if .Status}} · {{.Status}}{{end}}
+</div>
+<p>
+{{if .Passed}}<span class="badge pass">PASSED</span>{{else}}<span class="badge fail">FAILED</span>{{end}}
+{{if .AbortReason}} <strong>Aborted:</strong> {{.AbortReason}}{{end}}
+</p>
+
+<div class="cards">
+  <div class="card"><div class="label">Requests</div><div class="value">{{.TotalRequests}}</div></div>
+  <div class="card"><div class="label">Requests/s</div><div class="value">{{num .RPS}}</div></div>
+  <div class="card"><div class="label">Failed</div><div class="value">{{pct .ErrorRate}}</div></div>
+  <div class="card"><div class="label">p95 latency</div><div class="value">{{ms .Latency.P95}}</div></div>
+  <div class="card"><div class="label">p99 latency</div><div class="value">{{ms .Latency.P99}}</div></div>
+  <div class="card"><div class="label">Iterations</div><div class="value">{{.Iterations}}</div></div>
+  <div class="card"><div class="label">Data received</div><div class="value">{{bytes .DataReceived}}</div></div>
+  <div class="card"><div class="label">Data sent</div><div class="value">{{bytes .DataSent}}</div></div>
+</div>
+
+{{if .Charts}}
+<h2>Over time</h2>
+<div class="charts">
+{{range .Charts}}
+  <div class="chart">
+    <h3>{{.Title}}</h3>
+    <svg width="{{width}}" height="{{height}}" viewBox="0 0 {{width}} {{height}}" xmlns="http://www.w3.org/2000/svg">
+      {{range .Ticks}}
+      <line x1="{{left}}" x2="{{right}}" y1="{{.Y}}" y2="{{.Y}}" stroke="#e2e8f0"/>
+      <text x="{{left}}" y="{{.Y}}" dx="-6" dy="4" font-size="10" text-anchor="end" fill="#64748b">{{.Label}}</text>
+      {{end}}
+      <text x="{{right}}" y="{{bottom}}" dy="14" font-size="10" text-anchor="end" fill="#64748b">{{.XLabel}}</text>
+      {{range .Series}}
+      <polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Points}}"/>
+      {{end}}
+      {{range $i, $s := .Series}}
+      <rect x="{{legend $i}}" y="8" width="10" height="10" fill="{{$s.Color}}"/>
+      <text x="{{legend $i}}" y="17" dx="14" font-size="11" fill="#334155">{{$s.Name}}</text>
+      {{end}}
+    </svg>
+  </div>
+{{end}}
+</div>
+{{end}}
+
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+  <tr><th>Metric</th><th>Condition</th><th>Actual</th><th>Result</th></tr>
+  {{range .Thresholds}}
+  <tr><td>{{.Metric}}</td><td>{{.Condition}}</td><td class="num">{{num .Actual}}</td>
+  <td>{{if .Passed}}<span class="badge pass">pass</span>{{else}}<span class="badge fail">fail</span>{{end}}</td></tr>
+  {{end}}
+</table>
+{{end}}
+
+{{if .Checks}}
+<h2>Checks</h2>
+<table>
+  <tr><th>Check</th><th>Passes</th><th>Fails</th></tr>
+  {{range .Checks}}
+  <tr><td>{{.Name}}</td><td class="num">{{.Passes}}</td><td class="num">{{.Fails}}</td></tr>
+  {{end}}
+</table>
+{{end}}
+
+<h2>Latency</h2>
+<table>
+  <tr><th>Metric</th><th>avg</th><th>min</th><th>med</th><th>max</th><th>p(90)</th><th>p(95)</th><th>p(99)</th></tr>
+  <tr><td>http_req_duration</td>{{template "trend" .Latency}}</tr>
+  <tr><td>http_req_response_time</td>{{template "trend" .ResponseTime}}</tr>
+  {{range $name, $stats := .Timings}}
+  <tr><td>{{$name}}</td>{{template "trend" $stats}}</tr>
+  {{end}}
+</table>
+
+{{if .Steps}}
+<h2>Steps</h2>
+<table>
+  <tr><th>Step</th><th>Method</th><th>Requests</th><th>Failures</th><th>avg</th><th>min</th><th>med</th><th>max</th><th>p(90)</th><th>p(95)</th><th>p(99)</th></tr>
+  {{range .Steps}}
+  <tr><td>{{.Name}}</td><td>{{.Method}}</td><td class="num">{{.Requests}}</td><td class="num">{{.Failures}}</td>{{template "trend" .Latency}}</tr>
+  {{end}}
+</table>
+{{end}}
+
+{{if .Errors}}
+<h2>Errors</h2>
+<table>
+  <tr><th>Category</th><th>Count</th><th>First seen</th><th>Sample</th></tr>
+  {{range .Errors}}
+  <tr><td>{{.Category}}</td><td class="num">{{.Count}}</td><td>{{time .FirstSeen}}</td><td>{{.Sample}}</td></tr>
+  {{end}}
+</table>
+{{end}}
+
+{{if gt (len .Scenarios) 1}}
+<h2>Scenarios</h2>
+<table>
+  <tr><th>Scenario</th><th>Executor</th><th>Requests</th><th>Failures</th><th>Requests/s</th><th>p(95)</th><th>Dropped</th></tr>
+  {{range $name := .Scenarios}}{{with index $.TestResult.Scenarios $name}}
+  <tr><td>{{$name}}</td><td>{{.Executor}}</td><td class="num">{{.TotalRequests}}</td><td class="num">{{.Failure}}</td>
+  <td class="num">{{num .RPS}}</td><td class="num">{{ms .Latency.P95}}</td><td class="num">{{.DroppedIterations}}</td></tr>
+  {{end}}{{end}}
+</table>
+{{end}}
+
+{{if .Metrics}}
+<h2>Custom metrics</h2>
+<table>
+  <tr><th>Metric</th><th>Type</th><th>Tags</th><th>Values</th></tr>
+  {{range .Metrics}}
+  <tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{range $k, $v := .Tags}}{{$k}}={{$v}} {{end}}</td>
+  <td>{{range $k, $v := .Values}}{{$k}}={{num $v}} {{end}}</td></tr>
+  {{end}}
+</table>
+{{end}}
+</body>
+</html>
+{{define "trend"}}<td class="num">{{ms .Avg}}</td><td class="num">{{ms .Min}}</td><td class="num">{{ms .Med}}</td><td class="num">{{ms .Max}}</td><td class="num">{{ms .P90}}</td><td class="num">{{ms .P95}}</td><td class="num">{{ms .P99}}</td>{{end}}
+`))
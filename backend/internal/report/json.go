@@ -0,0 +1,20 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"k6clone/internal/model"
+)
+
+type jsonExporter struct{}
+
+func (jsonExporter) ContentType() string { return "application/json" }
+
+func (jsonExporter) Extension() string { return "json" }
+
+func (jsonExporter) Export(w io.Writer, result model.TestResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"k6clone/internal/model"
+)
+
+// junitExporter writes JUnit XML so CI can show a run's pass/fail: one
+// testcase per threshold and per check, plus one for the run itself, which
+// fails when the run was aborted.
+type junitExporter struct{}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+func (junitExporter) ContentType() string { return "application/xml" }
+
+func (junitExporter) Extension() string { return "xml" }
+
+func (junitExporter) Export(w io.Writer, result model.TestResult) error {
+	duration := formatFloat(runSeconds(result))
+
+	run := junitCase{Name: "run completed", ClassName: "run"}
+	if result.AbortReason != "" {
+		run.Failure = &junitFailure{Message: result.AbortReason, Type: "aborted"}
+	}
+
+	var thresholds []junitCase
+	for _, threshold := range result.Thresholds {
+		testcase := junitCase{Name: threshold.Metric + " " + threshold.Condition, ClassName: "thresholds"}
+		if !threshold.Passed {
+			testcase.Failure = &junitFailure{
+				Message: threshold.Aggregation + "=" + formatFloat(threshold.Actual) + ", want " + threshold.Condition,
+				Type:    "threshold",
+			}
+		}
+		thresholds = append(thresholds, testcase)
+	}
+
+	var checks []junitCase
+	for _, check := range result.Checks {
+		testcase := junitCase{Name: check.Name, ClassName: "checks"}
+		if check.Fails > 0 {
+			testcase.Failure = &junitFailure{
+				Message: strconv.Itoa(check.Fails) + " of " + strconv.Itoa(check.Passes+check.Fails) + " failed",
+				Type:    "check",
+			}
+		}
+		checks = append(checks, testcase)
+	}
+
+	suites := junitSuites{Name: "test " + result.TestID, Time: duration}
+	for _, suite := range []junitSuite{
+		{Name: "run", Cases: []junitCase{run}},
+		{Name: "thresholds", Cases: thresholds},
+		{Name: "checks", Cases: checks},
+	} {
+		suite.Time = duration
+		suite.Timestamp = result.StartedAt.UTC().Format("2006-01-02T15:04:05")
+		suite.Tests = len(suite.Cases)
+		for _, testcase := range suite.Cases {
+			if testcase.Failure != nil {
+				suite.Failures++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,488 @@
+package report
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"k6clone/internal/engine"
+	"k6clone/internal/model"
+)
+
+// k6TrendStats are the trend values this exporter writes, k6's default
+// summaryTrendStats plus p(99).
+var k6TrendStats = []string{"avg", "min", "med", "max", "p(90)", "p(95)", "p(99)"}
+
+// k6Summary is the data k6 passes to handleSummary. Steps have no groups, so
+// every check is in the root group.
+type k6Summary struct {
+	RootGroup k6Group             `json:"root_group"`
+	Options   k6Options           `json:"options"`
+	State     k6State             `json:"state"`
+	Metrics   map[string]k6Metric `json:"metrics"`
+}
+
+type k6Group struct {
+	Name   string    `json:"name"`
+	Path   string    `json:"path"`
+	ID     string    `json:"id"`
+	Groups []k6Group `json:"groups"`
+	Checks []k6Check `json:"checks"`
+}
+
+type k6Check struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	ID     string `json:"id"`
+	Passes int    `json:"passes"`
+	Fails  int    `json:"fails"`
+}
+
+type k6Options struct {
+	SummaryTrendStats []string `json:"summaryTrendStats"`
+	SummaryTimeUnit   string   `json:"summaryTimeUnit"`
+	NoColor           bool     `json:"noColor"`
+}
+
+type k6State struct {
+	IsStdOutTTY       bool    `json:"isStdOutTTY"`
+	IsStdErrTTY       bool    `json:"isStdErrTTY"`
+	TestRunDurationMs float64 `json:"testRunDurationMs"`
+}
+
+type k6Metric struct {
+	Type       model.MetricType       `json:"type"`
+	Contains   string                 `json:"contains"`
+	Values     map[string]float64     `json:"values"`
+	Thresholds map[string]k6Threshold `json:"thresholds,omitempty"`
+}
+
+type k6Threshold struct {
+	OK bool `json:"ok"`
+}
+
+// k6SummaryExporter writes a result in the schema of k6's handleSummary data,
+// so tools built for k6 summaries can read it.
+type k6SummaryExporter struct{}
+
+func (k6SummaryExporter) ContentType() string { return "application/json" }
+
+func (k6SummaryExporter) Extension() string { return "json" }
+
+func (k6SummaryExporter) Export(w io.Writer, result model.TestResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newK6Summary(result))
+}
+
+func newK6Summary(result model.TestResult) k6Summary {
+	s := result.Summary
+	metrics := make(map[string]k6Metric)
+
+	counter := func(name, contains string, count int, rate float64) {
+		metrics[name] = k6Metric{Type: model.MetricCounter, Contains: contains,
+			Values: map[string]float64{"count": float64(count), "rate": rate}}
+	}
+	rate := func(name string, passes, fails int) {
+		metrics[name] = k6Metric{Type: model.MetricRate, Contains: "default",
+			Values: map[string]float64{"rate": ratio(passes, passes+fails), "passes": float64(passes), "fails": float64(fails)}}
+	}
+	trend := func(name string, stats model.TrendStats) {
+		metrics[name] = k6Metric{Type: model.MetricTrend, Contains: "time", Values: trendValues(stats)}
+	}
+
+	seconds := runSeconds(result)
+	perSecond := func(n int) float64 {
+		if seconds <= 0 {
+			return 0
+		}
+		return float64(n) / seconds
+	}
+
+	counter("http_reqs", "default", s.TotalRequests, s.RPS)
+	counter("iterations", "default", s.Iterations, perSecond(s.Iterations))
+	if s.DroppedIterations > 0 {
+		counter("dropped_iterations", "default", s.DroppedIterations, perSecond(s.DroppedIterations))
+	}
+	counter("data_sent", "data", s.DataSent, s.DataSentRate)
+	counter("data_received", "data", s.DataReceived, s.DataReceivedRate)
+	// In k6 a rate's passes are its non-zero samples: failed requests here.
+	rate("http_req_failed", s.Failure, s.Success)
+	trend("http_req_duration", s.Latency)
+	trend("http_req_response_time", s.ResponseTime)
+	for name, stats := range s.Timings {
+		trend(name, stats)
+	}
+
+	root := k6Group{ID: k6ID(""), Groups: []k6Group{}, Checks: []k6Check{}}
+	passes, fails := 0, 0
+	for _, check := range s.Checks {
+		path := "::" + check.Name
+		root.Checks = append(root.Checks, k6Check{
+			Name: check.Name, Path: path, ID: k6ID(path), Passes: check.Passes, Fails: check.Fails,
+		})
+		passes += check.Passes
+		fails += check.Fails
+	}
+	if len(s.Checks) > 0 {
+		rate("checks", passes, fails)
+	}
+
+	if n := len(result.TimeSeries); n > 0 {
+		vus := k6Metric{Type: model.MetricGauge, Contains: "default", Values: map[string]float64{
+			"value": float64(result.TimeSeries[n-1].ActiveVUs),
+			"min":   math.Inf(1),
+			"max":   0,
+		}}
+		for _, snapshot := range result.TimeSeries {
+			vus.Values["min"] = min(vus.Values["min"], float64(snapshot.ActiveVUs))
+			vus.Values["max"] = max(vus.Values["max"], float64(snapshot.ActiveVUs))
+		}
+		metrics["vus"] = vus
+	}
+
+	for _, metric := range s.Metrics {
+		contains := "default"
+		if metric.Type == model.MetricCounter && strings.HasPrefix(metric.Name, "data_") {
+			contains = "data"
+		}
+		metrics[metricKey(metric.Name, metric.Tags)] = k6Metric{Type: metric.Type, Contains: contains, Values: metric.Values}
+	}
+
+	// Thresholds on sub-metrics get an entry of their own holding the value
+	// the threshold was checked against.
+	for _, threshold := range result.Thresholds {
+		metric, ok := metrics[threshold.Metric]
+		if !ok {
+			name, _, _ := engine.ParseMetric(threshold.Metric)
+			metric = k6Metric{Type: metrics[name].Type, Contains: metrics[name].Contains, Values: map[string]float64{}}
+		}
+		if _, ok := metric.Values[threshold.Aggregation]; !ok {
+			metric.Values[threshold.Aggregation] = threshold.Actual
+		}
+		if metric.Thresholds == nil {
+			metric.Thresholds = make(map[string]k6Threshold)
+		}
+		metric.Thresholds[threshold.Condition] = k6Threshold{OK: threshold.Passed}
+		metrics[threshold.Metric] = metric
+	}
+
+	return k6Summary{
+		RootGroup: root,
+		Options:   k6Options{SummaryTrendStats: k6TrendStats},
+		State:     k6State{TestRunDurationMs: seconds * 1000},
+		Metrics:   metrics,
+	}
+}
+
+func trendValues(stats model.TrendStats) map[string]float64 {
+	return map[string]float64{
+		"avg":   stats.Avg,
+		"min":   stats.Min,
+		"med":   stats.Med,
+		"max":   stats.Max,
+		"p(90)": stats.P90,
+		"p(95)": stats.P95,
+		"p(99)": stats.P99,
+	}
+}
+
+// k6ID is how k6 identifies groups and checks: the MD5 of their path.
+func k6ID(path string) string {
+	sum := md5.Sum([]byte(path))
+	return hex.EncodeToString(sum[:])
+}
+
+func metricKey(name string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return name
+	}
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, key+":"+tags[key])
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// k6ImportSummary is either k6's handleSummary data or the older
+// --summary-export layout, where values sit directly on each metric, the
+// root group's checks are a map and thresholds map to whether they failed.
+type k6ImportSummary struct {
+	RootGroup k6ImportGroup              `json:"root_group"`
+	State     k6State                    `json:"state"`
+	Metrics   map[string]json.RawMessage `json:"metrics"`
+}
+
+type k6ImportGroup struct {
+	Groups json.RawMessage `json:"groups"`
+	Checks json.RawMessage `json:"checks"`
+}
+
+type k6ImportMetric struct {
+	Type       model.MetricType           `json:"type"`
+	Values     map[string]float64         `json:"values"`
+	Thresholds map[string]json.RawMessage `json:"thresholds"`
+}
+
+// ImportK6Summary reads a k6 end-of-test summary into a test result. The
+// result has no ID, script or time series; the caller fills in what it knows.
+func ImportK6Summary(r io.Reader) (model.TestResult, error) {
+	var summary k6ImportSummary
+	if err := json.NewDecoder(r).Decode(&summary); err != nil {
+		return model.TestResult{}, errors.New("invalid k6 summary: " + err.Error())
+	}
+	if len(summary.Metrics) == 0 {
+		return model.TestResult{}, errors.New("invalid k6 summary: no metrics")
+	}
+
+	metrics := make(map[string]k6ImportMetric, len(summary.Metrics))
+	for name, raw := range summary.Metrics {
+		metric, err := decodeK6Metric(raw)
+		if err != nil {
+			return model.TestResult{}, errors.New("invalid k6 summary: metric " + name + ": " + err.Error())
+		}
+		metrics[name] = metric
+	}
+
+	checks, err := importK6Checks(summary.RootGroup)
+	if err != nil {
+		return model.TestResult{}, errors.New("invalid k6 summary: " + err.Error())
+	}
+
+	var s model.Summary
+	value := func(name, key string) float64 { return metrics[name].Values[key] }
+
+	s.TotalRequests = int(value("http_reqs", "count"))
+	s.RPS = value("http_reqs", "rate")
+	s.Failure = int(value("http_req_failed", "passes"))
+	s.Success = s.TotalRequests - s.Failure
+	s.Iterations = int(value("iterations", "count"))
+	s.DroppedIterations = int(value("dropped_iterations", "count"))
+	s.DataSent = int(value("data_sent", "count"))
+	s.DataSentRate = value("data_sent", "rate")
+	s.DataReceived = int(value("data_received", "count"))
+	s.DataReceivedRate = value("data_received", "rate")
+	s.Latency = trendStats(metrics["http_req_duration"].Values)
+	s.AvgLatencyMs = int64(math.Round(s.Latency.Avg))
+	s.P90LatencyMs = int64(math.Round(s.Latency.P90))
+	s.P95LatencyMs = int64(math.Round(s.Latency.P95))
+	s.P99LatencyMs = int64(math.Round(s.Latency.P99))
+	if metric, ok := metrics["http_req_response_time"]; ok {
+		s.ResponseTime = trendStats(metric.Values)
+	}
+	s.Checks = checks
+
+	mapped := []string{
+		"http_reqs", "http_req_failed", "iterations", "dropped_iterations", "data_sent",
+		"data_received", "http_req_duration", "http_req_response_time", "checks",
+	}
+	for _, name := range slices.Sorted(maps.Keys(metrics)) {
+		metric := metrics[name]
+		switch {
+		case slices.Contains(mapped, name):
+		case strings.HasPrefix(name, "http_req_") && !strings.Contains(name, "{") && metric.Type == model.MetricTrend:
+			if s.Timings == nil {
+				s.Timings = make(map[string]model.TrendStats)
+			}
+			s.Timings[name] = trendStats(metric.Values)
+		default:
+			base, tags, err := engine.ParseMetric(name)
+			if err != nil {
+				base = name
+			}
+			s.Metrics = append(s.Metrics, model.MetricResult{Name: base, Type: metric.Type, Tags: tags, Values: metric.Values})
+		}
+	}
+
+	result := model.TestResult{
+		Status:  model.Finished,
+		Summary: s,
+		Passed:  true,
+	}
+	for _, name := range slices.Sorted(maps.Keys(metrics)) {
+		metric := metrics[name]
+		for _, condition := range slices.Sorted(maps.Keys(metric.Thresholds)) {
+			passed, err := thresholdPassed(metric.Thresholds[condition])
+			if err != nil {
+				return model.TestResult{}, errors.New("invalid k6 summary: threshold " + condition + ": " + err.Error())
+			}
+
+			threshold, err := engine.NormalizeThreshold(model.Threshold{Metric: name, Condition: condition})
+			if err != nil {
+				threshold = model.Threshold{Metric: name, Condition: condition}
+			}
+			result.Thresholds = append(result.Thresholds, model.ThresholdResult{
+				Threshold: threshold,
+				Actual:    metric.Values[threshold.Aggregation],
+				Passed:    passed,
+			})
+			result.Passed = result.Passed && passed
+		}
+	}
+
+	result.DurationSec = summary.State.TestRunDurationMs / 1000
+	if result.DurationSec == 0 {
+		result.DurationSec = durationFromCounters(metrics)
+	}
+	result.StartedAt = time.Now().Add(-time.Duration(result.DurationSec * float64(time.Second)))
+	return result, nil
+}
+
+// durationFromCounters recovers the run duration of the older layout, which
+// has no state, from a counter's count and per-second rate.
+func durationFromCounters(metrics map[string]k6ImportMetric) float64 {
+	for _, name := range []string{"iterations", "http_reqs", "data_sent"} {
+		values := metrics[name].Values
+		if values["count"] > 0 && values["rate"] > 0 {
+			return values["count"] / values["rate"]
+		}
+	}
+	return 0
+}
+
+// decodeK6Metric reads a metric in either layout. The older one has no
+// "values" object and mixes its values with "thresholds".
+func decodeK6Metric(raw json.RawMessage) (k6ImportMetric, error) {
+	var metric k6ImportMetric
+	if err := json.Unmarshal(raw, &metric); err != nil {
+		return metric, err
+	}
+	if metric.Values != nil {
+		return metric, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return metric, err
+	}
+	metric.Values = make(map[string]float64)
+	for key, field := range fields {
+		var v float64
+		if json.Unmarshal(field, &v) == nil {
+			metric.Values[key] = v
+		}
+	}
+
+	// The older layout reports a rate's ratio as "value" and has no type.
+	if _, ok := metric.Values["passes"]; ok {
+		metric.Type = model.MetricRate
+		metric.Values["rate"] = metric.Values["value"]
+		delete(metric.Values, "value")
+	}
+	if metric.Type == "" {
+		metric.Type = legacyMetricType(metric.Values)
+	}
+	return metric, nil
+}
+
+func legacyMetricType(values map[string]float64) model.MetricType {
+	if _, ok := values["count"]; ok {
+		return model.MetricCounter
+	}
+	if _, ok := values["avg"]; ok {
+		return model.MetricTrend
+	}
+	return model.MetricGauge
+}
+
+// thresholdPassed reads {"ok": bool} from handleSummary data, or the older
+// layout's bare bool, which is true when the threshold failed.
+func thresholdPassed(raw json.RawMessage) (bool, error) {
+	var failed bool
+	if json.Unmarshal(raw, &failed) == nil {
+		return !failed, nil
+	}
+	var threshold k6Threshold
+	if err := json.Unmarshal(raw, &threshold); err != nil {
+		return false, err
+	}
+	return threshold.OK, nil
+}
+
+// importK6Checks collects the checks of a group and its subgroups. Checks in
+// subgroups are named by their path, such as "login::status is 200", since
+// steps have no groups. Groups and checks are arrays in handleSummary data and
+// maps keyed by name in the older layout.
+func importK6Checks(group k6ImportGroup) ([]model.CheckResult, error) {
+	var results []model.CheckResult
+	index := make(map[string]int)
+
+	var walk func(group k6ImportGroup) error
+	walk = func(group k6ImportGroup) error {
+		checks, err := listOrMap[k6Check](group.Checks)
+		if err != nil {
+			return err
+		}
+		for _, check := range checks {
+			name := check.Name
+			if check.Path != "" {
+				name = strings.TrimPrefix(check.Path, "::")
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(results)
+				index[name] = i
+				results = append(results, model.CheckResult{Name: name})
+			}
+			results[i].Passes += check.Passes
+			results[i].Fails += check.Fails
+		}
+
+		groups, err := listOrMap[k6ImportGroup](group.Groups)
+		if err != nil {
+			return err
+		}
+		for _, child := range groups {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(group); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// listOrMap decodes a JSON array, or the values of a JSON object in key order.
+func listOrMap[T any](raw json.RawMessage) ([]T, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var list []T
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+
+	var byName map[string]T
+	if err := json.Unmarshal(raw, &byName); err != nil {
+		return nil, err
+	}
+	for _, key := range slices.Sorted(maps.Keys(byName)) {
+		list = append(list, byName[key])
+	}
+	return list, nil
+}
+
+func trendStats(values map[string]float64) model.TrendStats {
+	return model.TrendStats{
+		Avg:  values["avg"],
+		Min:  values["min"],
+		Med:  values["med"],
+		Max:  values["max"],
+		P90:  values["p(90)"],
+		P95:  values["p(95)"],
+		P99:  values["p(99)"],
+		P999: values["p(99.9)"],
+	}
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// handleSummaryData is what k6 passes to handleSummary, trimmed to the
+// metrics the importer maps.
+const handleSummaryData = `{
+  "root_group": {"name": "", "path": "", "id": "d41d8cd98f00b204e9800998ecf8427e", "groups": [], "checks": [
+    {"name": "status is 200", "path": "::status is 200", "id": "1461660757a913d4fb3ed2ff1a7e1fe6", "passes": 95, "fails": 5}
+  ]},
+  "options": {"summaryTrendStats": ["avg", "min", "med", "max", "p(90)", "p(95)"], "summaryTimeUnit": "", "noColor": false},
+  "state": {"isStdOutTTY": false, "isStdErrTTY": false, "testRunDurationMs": 30000},
+  "metrics": {
+    "http_reqs": {"type": "counter", "contains": "default", "values": {"count": 600, "rate": 20}},
+    "iterations": {"type": "counter", "contains": "default", "values": {"count": 300, "rate": 10}},
+    "http_req_failed": {"type": "rate", "contains": "default", "values": {"rate": 0.05, "passes": 30, "fails": 570}},
+    "http_req_duration": {"type": "trend", "contains": "time",
+      "values": {"avg": 120, "min": 10, "med": 100, "max": 900, "p(90)": 200, "p(95)": 250},
+      "thresholds": {"p(95)<500": {"ok": true}}},
+    "checks": {"type": "rate", "contains": "default", "values": {"rate": 0.95, "passes": 95, "fails": 5}}
+  }
+}`
+
+// summaryExportData is the same run in the older --summary-export layout,
+// which has no state.
+const summaryExportData = `{
+  "root_group": {"name": "", "path": "", "id": "d41d8cd98f00b204e9800998ecf8427e", "groups": {}, "checks": {
+    "status is 200": {"name": "status is 200", "path": "::status is 200", "id": "1461660757a913d4fb3ed2ff1a7e1fe6", "passes": 95, "fails": 5}
+  }},
+  "metrics": {
+    "http_reqs": {"count": 600, "rate": 20},
+    "iterations": {"count": 300, "rate": 10},
+    "http_req_failed": {"passes": 30, "fails": 570, "value": 0.05},
+    "http_req_duration": {"avg": 120, "min": 10, "med": 100, "max": 900, "p(90)": 200, "p(95)": 250,
+      "thresholds": {"p(95)<500": false}},
+    "checks": {"passes": 95, "fails": 5, "value": 0.95}
+  }
+}`
+
+func TestK6SummaryRoundTrip(t *testing.T) {
+	for name, data := range map[string]string{
+		"handleSummary":    handleSummaryData,
+		"--summary-export": summaryExportData,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := ImportK6Summary(strings.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.DurationSec != 30 {
+				t.Errorf("duration = %vs, want 30s", result.DurationSec)
+			}
+			if result.TotalRequests != 600 || result.Failure != 30 || result.Iterations != 300 {
+				t.Errorf("summary = %d requests, %d failed, %d iterations, want 600, 30, 300",
+					result.TotalRequests, result.Failure, result.Iterations)
+			}
+			if len(result.Thresholds) != 1 || !result.Thresholds[0].Passed || !result.Passed {
+				t.Errorf("thresholds = %+v, want p(95)<500 passed", result.Thresholds)
+			}
+
+			var exported bytes.Buffer
+			if err := (k6SummaryExporter{}).Export(&exported, result); err != nil {
+				t.Fatal(err)
+			}
+			var summary k6Summary
+			if err := json.Unmarshal(exported.Bytes(), &summary); err != nil {
+				t.Fatal(err)
+			}
+			if summary.State.TestRunDurationMs != 30000 {
+				t.Errorf("testRunDurationMs = %v, want 30000", summary.State.TestRunDurationMs)
+			}
+			for metric, want := range map[string]map[string]float64{
+				"http_reqs":         {"count": 600, "rate": 20},
+				"iterations":        {"count": 300, "rate": 10},
+				"http_req_failed":   {"rate": 0.05, "passes": 30, "fails": 570},
+				"http_req_duration": {"avg": 120, "p(95)": 250},
+				"checks":            {"rate": 0.95, "passes": 95, "fails": 5},
+			} {
+				for key, value := range want {
+					if got := summary.Metrics[metric].Values[key]; got != value {
+						t.Errorf("%s %s = %v, want %v", metric, key, got, value)
+					}
+				}
+			}
+			if ok := summary.Metrics["http_req_duration"].Thresholds["p(95)<500"].OK; !ok {
+				t.Error("p(95)<500 not exported as ok")
+			}
+
+			reimported, err := ImportK6Summary(&exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reimported.DurationSec != 30 || reimported.Iterations != 300 {
+				t.Errorf("re-imported duration = %vs, iterations = %d, want 30s and 300", reimported.DurationSec, reimported.Iterations)
+			}
+
+			var html bytes.Buffer
+			if err := (htmlExporter{}).Export(&html, result); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(html.String(), "ran 30s") {
+				t.Error("html report lacks the 30s run duration")
+			}
+
+			var junit bytes.Buffer
+			if err := (junitExporter{}).Export(&junit, result); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(junit.String(), `<testsuites name="test " tests="3" failures="1" time="30">`) {
+				t.Errorf("junit report lacks the 30s run time:\n%s", junit.String())
+			}
+		})
+	}
+}
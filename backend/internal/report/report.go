@@ -0,0 +1,52 @@
+package report
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"k6clone/internal/model"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Exporter renders a stored test result in one file format.
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Export(w io.Writer, result model.TestResult) error
+}
+
+// NewExporter returns the exporter of a format: json, csv, junit, html
+// or k6, k6's end-of-test summary.
+func NewExporter(format string) (Exporter, error) {
+	switch format {
+	case "", "json":
+		return jsonExporter{}, nil
+	case "csv":
+		return csvExporter{}, nil
+	case "junit":
+		return junitExporter{}, nil
+	case "html":
+		return htmlExporter{}, nil
+	case "k6":
+		return k6SummaryExporter{}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// runSeconds is how long the run lasted. Results stored before the duration
+// was recorded fall back to their time series.
+func runSeconds(result model.TestResult) float64 {
+	if result.DurationSec > 0 {
+		return result.DurationSec
+	}
+	if n := len(result.TimeSeries); n > 0 {
+		return result.TimeSeries[n-1].ElapsedSec
+	}
+	return 0
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
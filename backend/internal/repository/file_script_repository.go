@@ -32,9 +32,12 @@ func (r *FileScriptRepository) Save(script *model.Script) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.data[script.ID] = script
+	if err := r.saveToDisk(script); err != nil {
+		return err
+	}
 
-	return r.saveToDisk(script)
+	r.data[script.ID] = script
+	return nil
 }
 
 func (r *FileScriptRepository) FindByID(id string) (*model.Script, error) {
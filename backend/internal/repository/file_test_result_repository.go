@@ -2,6 +2,7 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"sort"
@@ -77,6 +78,43 @@ func (r *FileTestResultRepository) FindAll() []model.TestResult {
 	return results
 }
 
+// FindByID reads only the files named after testID, which generateFilename
+// puts last. IDs that could widen the pattern or leave the directory never
+// match.
+func (r *FileTestResultRepository) FindByID(testID string) (model.TestResult, error) {
+	notFound := errors.New("test result not found")
+	if testID == "" || strings.ContainsAny(testID, `*?[]\/`) {
+		return model.TestResult{}, notFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(r.resultsDir, "result-*-"+testID+".json"))
+	if err != nil {
+		return model.TestResult{}, notFound
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var result model.TestResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			continue
+		}
+
+		// A dashed ID may also be the tail of a longer one.
+		if result.TestID == testID {
+			return result, nil
+		}
+	}
+
+	return model.TestResult{}, notFound
+}
+
 func (r *FileTestResultRepository) FindByScriptID(scriptID string) []model.TestResult {
 	all := r.FindAll()
 	var filtered []model.TestResult
@@ -92,7 +130,7 @@ func (r *FileTestResultRepository) FindByScriptID(scriptID string) []model.TestR
 
 func (r *FileTestResultRepository) generateFilename(result model.TestResult) string {
 	timestamp := result.StartedAt.Format("20060102-150405")
-	return "result-" + result.ScriptID + "-" + timestamp + ".json"
+	return "result-" + result.ScriptID + "-" + timestamp + "-" + result.TestID + ".json"
 }
 
 func (r *FileTestResultRepository) Cleanup(olderThan time.Duration) error {
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"k6clone/internal/model"
+)
+
+func TestFileTestResultRepositoryFindByID(t *testing.T) {
+	repo := NewFileTestResultRepository(t.TempDir())
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, id := range []string{"a1b2-c3d4", "c3d4", "e5f6"} {
+		repo.Save(model.TestResult{TestID: id, ScriptID: "script-1", StartedAt: started})
+	}
+
+	for _, id := range []string{"a1b2-c3d4", "c3d4", "e5f6"} {
+		result, err := repo.FindByID(id)
+		if err != nil {
+			t.Errorf("FindByID(%q): %v", id, err)
+			continue
+		}
+		if result.TestID != id {
+			t.Errorf("FindByID(%q) = %q", id, result.TestID)
+		}
+	}
+
+	for _, id := range []string{"", "missing", "*", "c3d?", "../c3d4"} {
+		if result, err := repo.FindByID(id); err == nil {
+			t.Errorf("FindByID(%q) = %q, want not found", id, result.TestID)
+		}
+	}
+}
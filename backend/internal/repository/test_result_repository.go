@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"sync"
 
 	"k6clone/internal/model"
@@ -9,6 +10,7 @@ import (
 type TestResultRepository interface {
 	Save(result model.TestResult)
 	FindAll() []model.TestResult
+	FindByID(testID string) (model.TestResult, error)
 	FindByScriptID(scriptID string) []model.TestResult
 }
 
@@ -35,6 +37,18 @@ func (r *MemoryTestResultRepository) FindAll() []model.TestResult {
 	return r.results
 }
 
+func (r *MemoryTestResultRepository) FindByID(testID string) (model.TestResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, result := range r.results {
+		if result.TestID == testID {
+			return result, nil
+		}
+	}
+	return model.TestResult{}, errors.New("test result not found")
+}
+
 func (r *MemoryTestResultRepository) FindByScriptID(scriptID string) []model.TestResult {
 	r.mu.Lock()
 	defer r.mu.Unlock()
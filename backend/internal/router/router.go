@@ -18,15 +18,20 @@ func NewRouter(
 
 	mux := http.NewServeMux()
 
-	scriptHandler := handlers.NewScriptHandler(scriptService, k6Gen)
+	scriptHandler := handlers.NewScriptHandler(scriptService, testService, k6Gen)
 	testHandler := handlers.NewTestHandler(testService)
 	historyHandler := handlers.NewHistoryHandler(historyRepo)
+	metricsHandler := handlers.NewMetricsHandler(testService, scriptService)
 
 	mux.HandleFunc("/scripts", scriptHandler.HandleScripts)
-	mux.HandleFunc("/scripts/", scriptHandler.GetScriptByID)
+	mux.HandleFunc("/scripts/", scriptHandler.HandleScript)
 	mux.HandleFunc("/scripts/k6", scriptHandler.GetK6Script)
 	mux.HandleFunc("/tests/run", testHandler.RunTest)
+	mux.HandleFunc("/tests/import", testHandler.ImportTest)
+	mux.HandleFunc("/tests/compare", testHandler.CompareTests)
+	mux.HandleFunc("/tests/", testHandler.HandleTest)
 	mux.HandleFunc("/history", historyHandler.GetHistory)
+	mux.HandleFunc("/metrics", metricsHandler.GetMetrics)
 	mux.HandleFunc("/health", health)
 
 	return mux
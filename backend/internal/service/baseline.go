@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"k6clone/internal/model"
+	"k6clone/internal/report"
+)
+
+var (
+	ErrScriptNotFound = errors.New("script not found")
+	ErrNoBaseline     = errors.New("script has no baseline")
+)
+
+// CompareTests compares finished tests side by side, against the first one.
+func (s *TestService) CompareTests(testIDs []string) (model.Comparison, error) {
+	if len(testIDs) < 2 {
+		return model.Comparison{}, errors.New("at least two test ids required")
+	}
+
+	var results []model.TestResult
+	for _, testID := range testIDs {
+		result, err := s.GetResult(testID)
+		if err != nil {
+			return model.Comparison{}, fmt.Errorf("%w: %s", err, testID)
+		}
+		results = append(results, result)
+	}
+
+	return report.Compare(results), nil
+}
+
+func (s *TestService) GetBaseline(scriptID string) (model.Baseline, error) {
+	script, err := s.scriptRepo.FindByID(scriptID)
+	if err != nil {
+		return model.Baseline{}, ErrScriptNotFound
+	}
+	if script.Baseline == nil {
+		return model.Baseline{}, ErrNoBaseline
+	}
+	return *script.Baseline, nil
+}
+
+// SetBaseline makes a finished run of the script the one its later runs are
+// checked against.
+func (s *TestService) SetBaseline(scriptID string, baseline model.Baseline) (model.Baseline, error) {
+	if err := ValidateBaseline(baseline); err != nil {
+		return model.Baseline{}, err
+	}
+
+	script, err := s.scriptRepo.FindByID(scriptID)
+	if err != nil {
+		return model.Baseline{}, ErrScriptNotFound
+	}
+
+	result, err := s.GetResult(baseline.TestID)
+	if err != nil {
+		return model.Baseline{}, errors.New("baseline " + baseline.TestID + ": " + err.Error())
+	}
+	if result.ScriptID != scriptID {
+		return model.Baseline{}, errors.New("baseline " + baseline.TestID + ": not a run of script " + scriptID)
+	}
+	if result.Status == model.Aborted {
+		return model.Baseline{}, errors.New("baseline " + baseline.TestID + ": run was aborted")
+	}
+
+	// The repository hands out its shared script; runs read it concurrently.
+	updated := *script
+	updated.Baseline = &baseline
+	if err := s.scriptRepo.Save(&updated); err != nil {
+		return model.Baseline{}, err
+	}
+	return baseline, nil
+}
+
+func (s *TestService) ClearBaseline(scriptID string) error {
+	script, err := s.scriptRepo.FindByID(scriptID)
+	if err != nil {
+		return ErrScriptNotFound
+	}
+	if script.Baseline == nil {
+		return ErrNoBaseline
+	}
+
+	updated := *script
+	updated.Baseline = nil
+	return s.scriptRepo.Save(&updated)
+}
+
+// checkRegression compares a finished run with its script's baseline, if the
+// script has one and its run is still stored.
+func (s *TestService) checkRegression(result model.TestResult) *model.RegressionReport {
+	if result.ScriptID == "" || result.Status != model.Finished {
+		return nil
+	}
+
+	script, err := s.scriptRepo.FindByID(result.ScriptID)
+	if err != nil || script.Baseline == nil || script.Baseline.TestID == result.TestID {
+		return nil
+	}
+
+	reference, err := s.resultRepo.FindByID(script.Baseline.TestID)
+	if err != nil {
+		return nil
+	}
+	return report.CheckRegression(*script.Baseline, reference, result)
+}
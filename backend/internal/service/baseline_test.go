@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"k6clone/internal/engine"
+	"k6clone/internal/model"
+	"k6clone/internal/repository"
+)
+
+// failingScriptRepository rejects every save.
+type failingScriptRepository struct {
+	*repository.MemoryScriptRepository
+}
+
+func (r failingScriptRepository) Save(script *model.Script) error {
+	return errors.New("disk full")
+}
+
+func newBaselineService(t *testing.T, scripts repository.ScriptRepository) *TestService {
+	t.Helper()
+	results := repository.NewMemoryTestResultRepository()
+	results.Save(model.TestResult{TestID: "run-1", ScriptID: "script-1", Status: model.Finished})
+	return NewTestService(scripts, results, engine.NewLoadEngine(), t.TempDir())
+}
+
+func TestSetBaselineLeavesSharedScriptAlone(t *testing.T) {
+	scripts := repository.NewMemoryScriptRepository()
+	scripts.Save(&model.Script{ID: "script-1"})
+	shared, _ := scripts.FindByID("script-1")
+	tests := newBaselineService(t, scripts)
+
+	if _, err := tests.SetBaseline("script-1", model.Baseline{TestID: "run-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if shared.Baseline != nil {
+		t.Error("SetBaseline changed the script other readers hold")
+	}
+	if baseline, err := tests.GetBaseline("script-1"); err != nil || baseline.TestID != "run-1" {
+		t.Errorf("GetBaseline = %+v, %v, want run-1", baseline, err)
+	}
+
+	stored, _ := scripts.FindByID("script-1")
+	if err := tests.ClearBaseline("script-1"); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Baseline == nil {
+		t.Error("ClearBaseline changed the script other readers hold")
+	}
+	if _, err := tests.GetBaseline("script-1"); !errors.Is(err, ErrNoBaseline) {
+		t.Errorf("GetBaseline after clear: %v, want %v", err, ErrNoBaseline)
+	}
+}
+
+func TestSetBaselineKeepsScriptWhenSaveFails(t *testing.T) {
+	memory := repository.NewMemoryScriptRepository()
+	memory.Save(&model.Script{ID: "script-1"})
+	tests := newBaselineService(t, failingScriptRepository{memory})
+
+	if _, err := tests.SetBaseline("script-1", model.Baseline{TestID: "run-1"}); err == nil {
+		t.Fatal("SetBaseline succeeded, want the save error")
+	}
+	if _, err := tests.GetBaseline("script-1"); !errors.Is(err, ErrNoBaseline) {
+		t.Errorf("GetBaseline: %v, want no baseline after the failed save", err)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import "k6clone/internal/model"
+
+const (
+	smokeMaxDuration     = 30
+	defaultTestDuration  = 60
+	defaultTestTargetVUs = 10
+)
+
+// ExpandProfile turns config.Type into a concrete load profile derived from the
+// requested VUs and Duration. An explicit executor, stage list or scenario map
+// always wins over the preset.
+func ExpandProfile(config model.TestConfig) model.TestConfig {
+	if config.Type == "" || config.Executor != "" || len(config.Stages) > 0 || len(config.Scenarios) > 0 {
+		return config
+	}
+
+	target := config.VUs
+	if target <= 0 {
+		target = defaultTestTargetVUs
+	}
+	duration := config.Duration
+	if duration <= 0 {
+		duration = defaultTestDuration
+	}
+
+	switch config.Type {
+	case model.Smoke:
+		config.Executor = model.ConstantVUs
+		config.VUs = 1
+		config.Duration = min(duration, smokeMaxDuration)
+	case model.Load:
+		config.Executor = model.RampingVUs
+		config.Stages = loadStages(target, duration)
+	case model.Soak:
+		config.Executor = model.RampingVUs
+		config.Stages = soakStages(target, duration)
+	case model.Stress:
+		config.Executor = model.RampingVUs
+		config.Stages = stressStages(target, duration)
+	case model.Spike:
+		config.Executor = model.RampingVUs
+		config.Stages = spikeStages(target, duration)
+	default:
+		return config
+	}
+
+	return config
+}
+
+// loadStages ramps up to the target over the first 10% of the run, holds it,
+// and ramps down over the last 10%.
+func loadStages(target, duration int) []model.Stage {
+	ramp := max(duration/10, 1)
+
+	return []model.Stage{
+		{Duration: ramp, Target: target},
+		{Duration: max(duration-2*ramp, 1), Target: target},
+		{Duration: ramp, Target: 0},
+	}
+}
+
+// soakStages holds the target for almost the whole run with short ramps, so
+// long runs surface leaks and degradation rather than ramp behaviour.
+func soakStages(target, duration int) []model.Stage {
+	ramp := min(max(duration/20, 1), 300)
+
+	return []model.Stage{
+		{Duration: ramp, Target: target},
+		{Duration: max(duration-2*ramp, 1), Target: target},
+		{Duration: ramp, Target: 0},
+	}
+}
+
+// stressStages climbs in steps of half the target up to twice the target, so
+// the run keeps pushing past the expected load, then recovers to zero.
+func stressStages(target, duration int) []model.Stage {
+	levels := []int{target / 2, target, target * 3 / 2, target * 2}
+	step := max(duration/(len(levels)+1), 2)
+	ramp := max(step/4, 1)
+
+	var stages []model.Stage
+	for _, level := range levels {
+		stages = append(stages,
+			model.Stage{Duration: ramp, Target: max(level, 1)},
+			model.Stage{Duration: step - ramp, Target: max(level, 1)},
+		)
+	}
+
+	return append(stages, model.Stage{Duration: step, Target: 0})
+}
+
+// spikeStages holds a small baseline, jumps to the target almost instantly,
+// holds the spike briefly and drops back to the baseline before finishing.
+func spikeStages(target, duration int) []model.Stage {
+	baseline := max(target/10, 1)
+	edge := max(duration/20, 1)
+
+	return []model.Stage{
+		{Duration: max(duration/5, 1), Target: baseline},
+		{Duration: edge, Target: target},
+		{Duration: max(duration*3/10, 1), Target: target},
+		{Duration: edge, Target: baseline},
+		{Duration: max(duration*3/10, 1), Target: baseline},
+		{Duration: edge, Target: 0},
+	}
+}
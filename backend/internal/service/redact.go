@@ -0,0 +1,64 @@
+package service
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"k6clone/internal/model"
+)
+
+const redacted = "redacted"
+
+// credentialParams are the query parameters output URLs carry secrets in,
+// such as InfluxDB 1.x's u and p.
+var credentialParams = []string{"u", "p", "password", "token", "apikey", "api_key"}
+
+// redactConfig returns config with the credentials of its outputs masked, for
+// the copy a run or result keeps. Header names are kept, their values are
+// not; URLs lose their password and credential query parameters.
+func redactConfig(config model.TestConfig) model.TestConfig {
+	if len(config.Outputs) == 0 {
+		return config
+	}
+
+	outputs := make([]model.OutputConfig, len(config.Outputs))
+	for i, output := range config.Outputs {
+		if output.Headers != nil {
+			headers := make(map[string]string, len(output.Headers))
+			for key := range maps.Keys(output.Headers) {
+				headers[key] = redacted
+			}
+			output.Headers = headers
+		}
+		output.URL = redactURL(output.URL)
+		outputs[i] = output
+	}
+	config.Outputs = outputs
+	return config
+}
+
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+
+	query := u.Query()
+	masked := false
+	for key := range query {
+		if slices.Contains(credentialParams, strings.ToLower(key)) {
+			query.Set(key, redacted)
+			masked = true
+		}
+	}
+	if masked {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
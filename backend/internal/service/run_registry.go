@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"k6clone/internal/engine"
+	"k6clone/internal/model"
+)
+
+type runEntry struct {
+	run    model.TestRun
+	cancel context.CancelFunc
+	live   *engine.Broadcaster
+	done   chan struct{}
+}
+
+// RunRegistry tracks the runs that have not been persisted yet. Finished runs
+// are dropped from it once their result is in the result repository.
+type RunRegistry struct {
+	mu   sync.RWMutex
+	runs map[string]*runEntry
+}
+
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{
+		runs: make(map[string]*runEntry),
+	}
+}
+
+func (r *RunRegistry) add(run model.TestRun, cancel context.CancelFunc, live *engine.Broadcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.TestID] = &runEntry{run: run, cancel: cancel, live: live, done: make(chan struct{})}
+}
+
+func (r *RunRegistry) update(testID string, fn func(run *model.TestRun)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.runs[testID]; ok {
+		fn(&entry.run)
+	}
+}
+
+func (r *RunRegistry) remove(testID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.runs[testID]; ok {
+		close(entry.done)
+		delete(r.runs, testID)
+	}
+}
+
+func (r *RunRegistry) Get(testID string) (model.TestRun, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.runs[testID]
+	if !ok {
+		return model.TestRun{}, false
+	}
+	return entry.run, true
+}
+
+func (r *RunRegistry) Live(testID string) (*engine.Broadcaster, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.runs[testID]
+	if !ok {
+		return nil, false
+	}
+	return entry.live, true
+}
+
+// Cancel stops a run. The returned channel is closed once the run has been
+// persisted and dropped from the registry.
+func (r *RunRegistry) Cancel(testID string) (<-chan struct{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.runs[testID]
+	if !ok {
+		return nil, false
+	}
+	entry.cancel()
+	return entry.done, true
+}
+
+func (r *RunRegistry) Active() []model.TestRun {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runs := make([]model.TestRun, 0, len(r.runs))
+	for _, entry := range r.runs {
+		runs = append(runs, entry.run)
+	}
+	return runs
+}
@@ -1,43 +1,265 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 	"k6clone/internal/engine"
 	"k6clone/internal/model"
+	"k6clone/internal/report"
 	"k6clone/internal/repository"
 )
 
+var (
+	ErrTestNotFound   = errors.New("test not found")
+	ErrTestNotRunning = errors.New("test is not running")
+	ErrTestRunning    = errors.New("test is still running")
+)
+
 type TestService struct {
 	scriptRepo repository.ScriptRepository
 	resultRepo repository.TestResultRepository
 	engine     *engine.LoadEngine
+	runs       *RunRegistry
+	outputDir  string
+
+	// completed counts the runs that ended since the server started, by
+	// status, for /metrics. Imported results are not runs of this server.
+	mu        sync.Mutex
+	completed map[model.RunStatus]int
 }
 
 func NewTestService(
 	scriptRepo repository.ScriptRepository,
 	resultRepo repository.TestResultRepository,
 	engine *engine.LoadEngine,
+	outputDir string,
 ) *TestService {
 	return &TestService{
 		scriptRepo: scriptRepo,
 		resultRepo: resultRepo,
 		engine:     engine,
+		runs:       NewRunRegistry(),
+		outputDir:  outputDir,
+		completed:  map[model.RunStatus]int{model.Finished: 0, model.Aborted: 0},
 	}
 }
 
-func (s *TestService) RunTest(config model.TestConfig) (model.TestResult, error) {
-	script, err := s.scriptRepo.FindByID(config.ScriptID)
+// RunTest validates config and starts the run in the background. The returned
+// run carries the test ID used to poll or stop it.
+func (s *TestService) RunTest(config model.TestConfig) (model.TestRun, error) {
+	config = ExpandProfile(config)
+
+	if err := ValidateConfig(config); err != nil {
+		return model.TestRun{}, err
+	}
+
+	scripts, err := s.loadScripts(config)
 	if err != nil {
-		return model.TestResult{}, err
+		return model.TestRun{}, err
 	}
 
-	if err := ValidateScript(script); err != nil {
-		return model.TestResult{}, err
+	if err := engine.ValidateThresholds(config.Thresholds, scripts); err != nil {
+		return model.TestRun{}, err
 	}
 
-	result := s.engine.Run(script, config)
+	outputs, err := engine.NewOutputs(s.outputDir, config.Outputs)
+	if err != nil {
+		return model.TestRun{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	run := model.TestRun{
+		TestID:   uuid.NewString(),
+		ScriptID: config.ScriptID,
+		Status:   model.Queued,
+		Config:   redactConfig(config),
+		QueuedAt: time.Now(),
+	}
+	live := engine.NewBroadcaster()
+	s.runs.add(run, cancel, live)
+
+	go s.execute(ctx, cancel, live, outputs, run.TestID, scripts, config)
+
+	return run, nil
+}
+
+func (s *TestService) execute(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	live *engine.Broadcaster,
+	outputs []engine.Output,
+	testID string,
+	scripts map[string]*model.Script,
+	config model.TestConfig,
+) {
+	defer cancel()
+
+	startedAt := time.Now()
+	s.runs.update(testID, func(run *model.TestRun) {
+		run.Status = model.Running
+		run.StartedAt = &startedAt
+	})
+
+	result := s.engine.Run(ctx, scripts, config, live, outputs)
+	result.TestID = testID
+	result.Config = redactConfig(result.Config)
+	result.Status = model.Finished
+	if ctx.Err() != nil && result.AbortReason == "" {
+		result.AbortReason = "stopped by user"
+	}
+	if result.AbortReason != "" {
+		result.Status = model.Aborted
+	}
+	result.Regression = s.checkRegression(result)
 
 	s.resultRepo.Save(result)
+	s.countCompleted(result.Status)
+	s.runs.remove(testID)
+}
+
+func (s *TestService) GetTest(testID string) (model.TestRun, error) {
+	if run, ok := s.runs.Get(testID); ok {
+		return run, nil
+	}
+
+	result, err := s.resultRepo.FindByID(testID)
+	if err != nil {
+		return model.TestRun{}, ErrTestNotFound
+	}
+
+	return runFromResult(result), nil
+}
+
+// GetResult returns the stored result of a finished test.
+func (s *TestService) GetResult(testID string) (model.TestResult, error) {
+	if _, ok := s.runs.Get(testID); ok {
+		return model.TestResult{}, ErrTestRunning
+	}
+
+	result, err := s.resultRepo.FindByID(testID)
+	if err != nil {
+		return model.TestResult{}, ErrTestNotFound
+	}
+	return result, nil
+}
+
+// StopTest cancels a queued or running test. The run keeps its partial
+// metrics and is persisted with the aborted status, which the returned run
+// shows: requests in flight are cancelled too, so it ends promptly.
+func (s *TestService) StopTest(testID string) (model.TestRun, error) {
+	if done, ok := s.runs.Cancel(testID); ok {
+		<-done
+		return s.GetTest(testID)
+	}
+
+	if _, err := s.resultRepo.FindByID(testID); err == nil {
+		return model.TestRun{}, ErrTestNotRunning
+	}
+	return model.TestRun{}, ErrTestNotFound
+}
+
+// SubscribeLive streams the per-second snapshots of a queued or running test.
+// The channel is closed when the run ends; the returned func unsubscribes.
+func (s *TestService) SubscribeLive(testID string) (<-chan model.Snapshot, func(), error) {
+	live, ok := s.runs.Live(testID)
+	if !ok {
+		if _, err := s.resultRepo.FindByID(testID); err == nil {
+			return nil, nil, ErrTestNotRunning
+		}
+		return nil, nil, ErrTestNotFound
+	}
 
+	snapshots, unsubscribe := live.Subscribe()
+	return snapshots, unsubscribe, nil
+}
+
+// LiveMetrics returns the latest snapshot and the per-scenario metrics so far
+// of a running test. It reports false for tests that are not running.
+func (s *TestService) LiveMetrics(testID string) (model.Snapshot, map[string]model.ScenarioResult, bool) {
+	live, ok := s.runs.Live(testID)
+	if !ok {
+		return model.Snapshot{}, nil, false
+	}
+
+	scenarios, ok := live.Scenarios()
+	if !ok {
+		return model.Snapshot{}, nil, false
+	}
+	snapshot, _ := live.Latest()
+	return snapshot, scenarios, true
+}
+
+func (s *TestService) countCompleted(status model.RunStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed[status]++
+}
+
+// CompletedTests counts the results stored since the server started, by
+// status. Unlike the history it never goes down.
+func (s *TestService) CompletedTests() map[model.RunStatus]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.completed)
+}
+
+func (s *TestService) ActiveTests() []model.TestRun {
+	return s.runs.Active()
+}
+
+func (s *TestService) loadScripts(config model.TestConfig) (map[string]*model.Script, error) {
+	scripts := make(map[string]*model.Script)
+
+	for _, scenario := range engine.Scenarios(config) {
+		if _, ok := scripts[scenario.ScriptID]; ok {
+			continue
+		}
+
+		script, err := s.scriptRepo.FindByID(scenario.ScriptID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ValidateScript(script); err != nil {
+			return nil, err
+		}
+
+		scripts[scenario.ScriptID] = script
+	}
+
+	return scripts, nil
+}
+
+// ImportK6Summary stores a k6 end-of-test summary as a finished test so runs
+// made with k6 show up in the history, under scriptID when it is set.
+func (s *TestService) ImportK6Summary(r io.Reader, scriptID string) (model.TestResult, error) {
+	if scriptID != "" {
+		if _, err := s.scriptRepo.FindByID(scriptID); err != nil {
+			return model.TestResult{}, errors.New("script not found: " + scriptID)
+		}
+	}
+
+	result, err := report.ImportK6Summary(r)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	result.TestID = uuid.NewString()
+	result.ScriptID = scriptID
+	result.Config = model.TestConfig{ScriptID: scriptID, Tags: map[string]string{"source": "k6"}}
+	for _, threshold := range result.Thresholds {
+		result.Config.Thresholds = append(result.Config.Thresholds, threshold.Threshold)
+	}
+	result.Regression = s.checkRegression(result)
+
+	s.resultRepo.Save(result)
 	return result, nil
 }
 
@@ -47,4 +269,28 @@ func (s *TestService) GetTestHistory() []model.TestResult {
 
 func (s *TestService) GetScriptHistory(scriptID string) []model.TestResult {
 	return s.resultRepo.FindByScriptID(scriptID)
-}
\ No newline at end of file
+}
+
+func runFromResult(result model.TestResult) model.TestRun {
+	status := result.Status
+	if status == "" {
+		status = model.Finished
+	}
+
+	duration := result.DurationSec
+	if n := len(result.TimeSeries); duration == 0 && n > 0 {
+		duration = result.TimeSeries[n-1].ElapsedSec
+	}
+	finishedAt := result.StartedAt.Add(time.Duration(duration * float64(time.Second)))
+
+	return model.TestRun{
+		TestID:     result.TestID,
+		ScriptID:   result.ScriptID,
+		Status:     status,
+		Config:     result.Config,
+		QueuedAt:   result.StartedAt,
+		StartedAt:  &result.StartedAt,
+		FinishedAt: &finishedAt,
+		Result:     &result,
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k6clone/internal/engine"
+	"k6clone/internal/model"
+	"k6clone/internal/repository"
+)
+
+func TestStopTestReturnsStoppedRun(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	scripts := repository.NewMemoryScriptRepository()
+	scripts.Save(&model.Script{ID: "script-1", Steps: []model.Step{{Method: "GET", URL: target.URL}}})
+	tests := NewTestService(scripts, repository.NewMemoryTestResultRepository(), engine.NewLoadEngine(), t.TempDir())
+
+	var config model.TestConfig
+	config.ScriptID = "script-1"
+	config.Executor = model.ConstantVUs
+	config.VUs = 1
+	config.Duration = 60
+	started, err := tests.RunTest(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	run, err := tests.StopTest(started.TestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != model.Aborted {
+		t.Errorf("stopped run status = %s, want %s", run.Status, model.Aborted)
+	}
+	if run.Result == nil || run.Result.AbortReason != "stopped by user" {
+		t.Errorf("stopped run result = %+v, want it aborted by the user", run.Result)
+	}
+	if run.StartedAt == nil || run.FinishedAt == nil || run.FinishedAt.Before(run.StartedAt.Add(150*time.Millisecond)) {
+		t.Errorf("stopped run times = %v..%v, want it finished after running a while", run.StartedAt, run.FinishedAt)
+	}
+
+	if _, err := tests.StopTest(started.TestID); err != ErrTestNotRunning {
+		t.Errorf("second StopTest: %v, want %v", err, ErrTestNotRunning)
+	}
+}
+
+func TestRunFromResultFinishedAt(t *testing.T) {
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, result := range []model.TestResult{
+		{StartedAt: startedAt, DurationSec: 30},
+		{StartedAt: startedAt, TimeSeries: []model.Snapshot{{ElapsedSec: 1}, {ElapsedSec: 30}}},
+	} {
+		run := runFromResult(result)
+		if want := startedAt.Add(30 * time.Second); run.FinishedAt == nil || !run.FinishedAt.Equal(want) {
+			t.Errorf("finishedAt = %v, want %v", run.FinishedAt, want)
+		}
+	}
+}
@@ -2,6 +2,13 @@ package service
 
 import (
 	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+
+	"k6clone/internal/engine"
 	"k6clone/internal/model"
 )
 
@@ -21,6 +28,219 @@ func ValidateScript(script *model.Script) error {
 		if step.Method == "" {
 			return errors.New("step method is empty")
 		}
+		for _, check := range step.Checks {
+			if err := validateCheck(check); err != nil {
+				return err
+			}
+		}
+		for _, metric := range step.Metrics {
+			if err := validateStepMetric(step, metric); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateStepMetric(step model.Step, metric model.StepMetric) error {
+	if err := engine.ValidateMetricName(metric.Name); err != nil {
+		return err
+	}
+
+	switch metric.Type {
+	case model.MetricCounter, model.MetricGauge, model.MetricRate, model.MetricTrend:
+	default:
+		return errors.New("metric " + metric.Name + ": unknown type: " + string(metric.Type))
+	}
+
+	switch metric.Source {
+	case "", model.SourceValue, model.SourceDuration, model.SourceStatus:
+	case model.SourceJSONPath:
+		if metric.Path == "" {
+			return errors.New("metric " + metric.Name + ": jsonPath source requires a path")
+		}
+	case model.SourceCheck:
+		found := slices.ContainsFunc(engine.StepChecks(step), func(check model.Check) bool {
+			return engine.CheckName(check) == metric.Check
+		})
+		if !found {
+			return errors.New("metric " + metric.Name + ": step has no check named " + metric.Check)
+		}
+	default:
+		return errors.New("metric " + metric.Name + ": unknown source: " + string(metric.Source))
+	}
+
+	return nil
+}
+
+func validateCheck(check model.Check) error {
+	switch check.Type {
+	case model.CheckStatus, model.CheckBodyContains:
+	case model.CheckHeader:
+		if check.Header == "" {
+			return errors.New("header check requires a header name")
+		}
+	case model.CheckBodyMatches:
+		if _, err := regexp.Compile(check.Value); err != nil {
+			return errors.New("invalid body pattern: " + err.Error())
+		}
+	case model.CheckJSONPath:
+		if check.Path == "" {
+			return errors.New("jsonPath check requires a path")
+		}
+	case model.CheckMaxDuration:
+		if check.MaxDurationMs <= 0 {
+			return errors.New("maxDuration check requires a positive maxDurationMs")
+		}
+	default:
+		return errors.New("unknown check type: " + string(check.Type))
+	}
+
+	return nil
+}
+
+func ValidateConfig(config model.TestConfig) error {
+	switch config.Type {
+	case "", model.Smoke, model.Load, model.Stress, model.Spike, model.Soak:
+	default:
+		return errors.New("unknown test type: " + string(config.Type))
+	}
+
+	for _, threshold := range config.Thresholds {
+		if _, err := engine.NormalizeThreshold(threshold); err != nil {
+			return errors.New("threshold " + threshold.Metric + ": " + err.Error())
+		}
+		if threshold.DelayAbortEval < 0 {
+			return errors.New("threshold " + threshold.Metric + ": delayAbortEval must not be negative")
+		}
+	}
+
+	for _, output := range config.Outputs {
+		if err := validateOutput(output); err != nil {
+			return errors.New("output " + string(output.Type) + ": " + err.Error())
+		}
+	}
+
+	if len(config.Scenarios) == 0 {
+		return validateExecutor(config.ExecutorConfig)
+	}
+
+	for name, scenario := range engine.Scenarios(config) {
+		if scenario.ScriptID == "" {
+			return errors.New("scenario " + name + ": script id is empty")
+		}
+		if scenario.StartTime < 0 {
+			return errors.New("scenario " + name + ": startTime must not be negative")
+		}
+		if err := validateExecutor(scenario.ExecutorConfig); err != nil {
+			return errors.New("scenario " + name + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func ValidateBaseline(baseline model.Baseline) error {
+	if baseline.TestID == "" {
+		return errors.New("baseline test id is empty")
+	}
+	if baseline.TolerancePercent < 0 {
+		return errors.New("tolerancePercent must not be negative")
+	}
+
+	for _, tolerance := range baseline.Tolerances {
+		if tolerance.Metric == "" || tolerance.Stat == "" {
+			return errors.New("tolerance needs a metric and a stat")
+		}
+		if tolerance.Percent < 0 || tolerance.Absolute < 0 {
+			return errors.New("tolerance " + tolerance.Metric + " " + tolerance.Stat + ": must not be negative")
+		}
+	}
+
+	return nil
+}
+
+func validateOutput(output model.OutputConfig) error {
+	if output.Traces && output.Type != model.OutputOTLP {
+		return errors.New("traces are only exported by otlp outputs")
+	}
+
+	switch output.Type {
+	case model.OutputJSON, model.OutputCSV:
+		if err := engine.ValidateOutputFile(output.Path); err != nil {
+			return err
+		}
+	case model.OutputInflux:
+		u, err := url.Parse(output.URL)
+		if err != nil || u.Host == "" {
+			return errors.New("invalid url: " + output.URL)
+		}
+		switch u.Scheme {
+		case "http", "https", "udp":
+		default:
+			return errors.New("url scheme must be http, https or udp")
+		}
+	case model.OutputStatsD:
+		if _, _, err := net.SplitHostPort(output.Address); err != nil {
+			return errors.New("invalid address: " + output.Address)
+		}
+	case model.OutputOTLP:
+		u, err := url.Parse(output.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("invalid url: " + output.URL)
+		}
+	default:
+		return errors.New("unknown output type")
+	}
+
+	return nil
+}
+
+func validateExecutor(config model.ExecutorConfig) error {
+	switch engine.ResolveExecutor(config) {
+	case model.ConstantVUs:
+	case model.RampingVUs:
+		if len(config.Stages) == 0 {
+			return errors.New("ramping-vus requires at least one stage")
+		}
+	case model.ConstantArrivalRate:
+		if config.Rate <= 0 {
+			return errors.New("constant-arrival-rate requires a positive rate")
+		}
+	case model.RampingArrivalRate:
+		if len(config.Stages) == 0 {
+			return errors.New("ramping-arrival-rate requires at least one stage")
+		}
+	case model.PerVUIterations, model.SharedIterations:
+		if config.Iterations <= 0 {
+			return errors.New(string(config.Executor) + " requires a positive iteration count")
+		}
+	default:
+		return errors.New("unknown executor: " + string(config.Executor))
+	}
+
+	if config.TimeUnit != "" {
+		if d, err := time.ParseDuration(config.TimeUnit); err != nil || d <= 0 {
+			return errors.New("invalid time unit: " + config.TimeUnit)
+		}
+	}
+
+	if config.MaxDuration < 0 {
+		return errors.New("maxDuration must not be negative")
+	}
+
+	if config.MaxVUs > 0 && config.MaxVUs < config.PreAllocatedVUs {
+		return errors.New("maxVUs must not be lower than preAllocatedVUs")
+	}
+
+	for _, stage := range config.Stages {
+		if stage.Duration < 0 {
+			return errors.New("stage duration must not be negative")
+		}
+		if stage.Target < 0 {
+			return errors.New("stage target must not be negative")
+		}
 	}
 
 	return nil
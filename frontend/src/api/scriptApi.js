@@ -58,4 +58,30 @@ export const validateScript = async (script) => {
   });
   if (!response.ok) throw new Error('Failed to validate script');
   return response.json();
-};
\ No newline at end of file
+};
+export const getBaseline = async (scriptId) => {
+  const response = await fetch(`${API_BASE}/scripts/${scriptId}/baseline`);
+  if (response.status === 404) return null;
+  if (!response.ok) throw new Error('Failed to fetch baseline');
+  return response.json();
+};
+
+export const setBaseline = async (scriptId, baseline) => {
+  const response = await fetch(`${API_BASE}/scripts/${scriptId}/baseline`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(baseline)
+  });
+  if (!response.ok) {
+    const error = await response.text();
+    throw new Error(error || 'Failed to set baseline');
+  }
+  return response.json();
+};
+
+export const clearBaseline = async (scriptId) => {
+  const response = await fetch(`${API_BASE}/scripts/${scriptId}/baseline`, {
+    method: 'DELETE'
+  });
+  if (!response.ok) throw new Error('Failed to clear baseline');
+};
@@ -26,6 +26,13 @@ export const getTestResult = async (testId) => {
   return response.json();
 };
 
+export const subscribeLiveMetrics = (testId, onSnapshot) => {
+  const source = new EventSource(`${API_BASE}/tests/${testId}/live`);
+  source.addEventListener('snapshot', (event) => onSnapshot(JSON.parse(event.data)));
+  source.addEventListener('end', () => source.close());
+  return source;
+};
+
 export const stopTest = async (testId) => {
   const response = await fetch(`${API_BASE}/tests/${testId}/stop`, {
     method: 'POST'
@@ -56,9 +63,23 @@ export const exportTestResults = async (testId, format = 'json') => {
   const response = await fetch(`${API_BASE}/tests/${testId}/export?format=${format}`);
   if (!response.ok) throw new Error('Failed to export results');
   
-  if (format === 'json') {
+  if (format === 'json' || format === 'k6') {
     return response.json();
   }
   
   return response.blob();
 };
+
+export const importK6Summary = async (summary, scriptId) => {
+  const query = scriptId ? `?scriptId=${encodeURIComponent(scriptId)}` : '';
+  const response = await fetch(`${API_BASE}/tests/import${query}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: typeof summary === 'string' ? summary : JSON.stringify(summary)
+  });
+  if (!response.ok) {
+    const error = await response.text();
+    throw new Error(error || 'Failed to import k6 summary');
+  }
+  return response.json();
+};
@@ -2,6 +2,7 @@
 import { useState, useEffect, useRef } from "react";
 import { Activity, TrendingUp, TrendingDown, AlertCircle, CheckCircle } from "lucide-react";
 import { LineChart, Line, XAxis, YAxis, Tooltip, ResponsiveContainer, CartesianGrid } from "recharts";
+import { subscribeLiveMetrics } from "../api/testApi";
 
 export default function LiveMetrics({ testId, isRunning }) {
   const [metrics, setMetrics] = useState({
@@ -15,32 +16,27 @@ export default function LiveMetrics({ testId, isRunning }) {
 
   const [history, setHistory] = useState([]);
   const [alerts, setAlerts] = useState([]);
-  const intervalRef = useRef(null);
+  const sourceRef = useRef(null);
 
   useEffect(() => {
     if (!isRunning || !testId) {
-      if (intervalRef.current) {
-        clearInterval(intervalRef.current);
-      }
       return;
     }
 
-    // Simulate real-time metrics updates
-    // In production, this would connect to WebSocket
-    intervalRef.current = setInterval(() => {
+    const source = subscribeLiveMetrics(testId, (snapshot) => {
       const newMetrics = {
-        currentVUs: Math.floor(Math.random() * 100) + 10,
-        totalRequests: metrics.totalRequests + Math.floor(Math.random() * 50) + 10,
-        requestRate: Math.floor(Math.random() * 100) + 20,
-        errorRate: Math.random() * 5,
-        avgLatency: Math.floor(Math.random() * 200) + 100,
-        p95Latency: Math.floor(Math.random() * 400) + 200,
+        currentVUs: snapshot.activeVUs,
+        totalRequests: snapshot.totalRequests,
+        requestRate: Math.round(snapshot.rps),
+        errorRate: snapshot.errorRate * 100,
+        avgLatency: snapshot.avgLatencyMs,
+        p95Latency: snapshot.p95LatencyMs,
       };
 
       setMetrics(newMetrics);
 
       // Add to history
-      const timestamp = new Date().toLocaleTimeString();
+      const timestamp = new Date(snapshot.time).toLocaleTimeString();
       setHistory(prev => {
         const newHistory = [...prev, {
           time: timestamp,
@@ -67,14 +63,14 @@ export default function LiveMetrics({ testId, isRunning }) {
           message: `High p95 latency: ${newMetrics.p95Latency}ms`
         }, ...prev].slice(0, 5));
       }
-    }, 2000);
+    });
+    sourceRef.current = source;
 
     return () => {
-      if (intervalRef.current) {
-        clearInterval(intervalRef.current);
-      }
+      source.close();
+      sourceRef.current = null;
     };
-  }, [isRunning, testId, metrics.totalRequests]);
+  }, [isRunning, testId]);
 
   const getMetricTrend = (current, previous) => {
     if (!previous) return null;
@@ -8,6 +8,10 @@ import {
   PieChart,
   Pie,
   Cell,
+  LineChart,
+  Line,
+  CartesianGrid,
+  Legend,
 } from "recharts";
 
 const COLORS = ['#22c55e', '#dc2626'];
@@ -20,6 +24,24 @@ export default function ResultCharts({ result }) {
     { name: "Failure", value: result.failure || 0 },
   ];
 
+  const timeSeries = (result.timeSeries || []).map((point) => ({
+    elapsed: Math.round(point.elapsedSec),
+    rps: Number(point.rps.toFixed(1)),
+    vus: point.activeVUs,
+    errorRate: Number((point.errorRate * 100).toFixed(1)),
+    p50: point.p50LatencyMs,
+    p95: point.p95LatencyMs,
+    p99: point.p99LatencyMs,
+    p99Response: point.p99ResponseTimeMs,
+  }));
+
+  const tooltipStyle = {
+    background: '#020617',
+    border: '1px solid #1e293b',
+    borderRadius: '6px',
+    color: '#e5e7eb'
+  };
+
   const successRate = result.totalRequests > 0 
     ? ((result.success / result.totalRequests) * 100).toFixed(1)
     : 0;
@@ -106,6 +128,45 @@ export default function ResultCharts({ result }) {
         </div>
       </div>
 
+      {/* Time Series */}
+      {timeSeries.length > 0 && (
+        <div style={{ display: 'grid', gridTemplateColumns: 'repeat(auto-fit, minmax(300px, 1fr))', gap: '20px', marginTop: '24px' }}>
+          <div className="chart-box">
+            <h3>Latency Over Time (ms)</h3>
+            <ResponsiveContainer width="100%" height={250}>
+              <LineChart data={timeSeries}>
+                <CartesianGrid strokeDasharray="3 3" stroke="#334155" />
+                <XAxis dataKey="elapsed" stroke="#94a3b8" unit="s" />
+                <YAxis stroke="#94a3b8" />
+                <Tooltip contentStyle={tooltipStyle} />
+                <Legend />
+                <Line type="monotone" dataKey="p50" stroke="#22c55e" strokeWidth={2} dot={false} />
+                <Line type="monotone" dataKey="p95" stroke="#f59e0b" strokeWidth={2} dot={false} />
+                <Line type="monotone" dataKey="p99" stroke="#dc2626" strokeWidth={2} dot={false} />
+                <Line type="monotone" dataKey="p99Response" name="p99 response" stroke="#a855f7" strokeWidth={2} strokeDasharray="4 4" dot={false} />
+              </LineChart>
+            </ResponsiveContainer>
+          </div>
+
+          <div className="chart-box">
+            <h3>Throughput Over Time</h3>
+            <ResponsiveContainer width="100%" height={250}>
+              <LineChart data={timeSeries}>
+                <CartesianGrid strokeDasharray="3 3" stroke="#334155" />
+                <XAxis dataKey="elapsed" stroke="#94a3b8" unit="s" />
+                <YAxis yAxisId="left" stroke="#94a3b8" />
+                <YAxis yAxisId="right" orientation="right" stroke="#94a3b8" />
+                <Tooltip contentStyle={tooltipStyle} />
+                <Legend />
+                <Line yAxisId="left" type="monotone" dataKey="rps" name="RPS" stroke="#3b82f6" strokeWidth={2} dot={false} />
+                <Line yAxisId="right" type="monotone" dataKey="vus" name="VUs" stroke="#a855f7" strokeWidth={2} dot={false} />
+                <Line yAxisId="right" type="monotone" dataKey="errorRate" name="Error %" stroke="#dc2626" strokeWidth={2} dot={false} />
+              </LineChart>
+            </ResponsiveContainer>
+          </div>
+        </div>
+      )}
+
       {/* Test Info */}
       <div className="card" style={{ marginTop: '24px', background: '#0f172a' }}>
         <h3 style={{ fontSize: '16px', marginBottom: '12px' }}>Test Details</h3>
@@ -6,7 +6,6 @@ const METRIC_TYPES = [
   { value: 'http_req_failed', label: 'HTTP Request Failed', unit: 'rate' },
   { value: 'http_reqs', label: 'HTTP Requests', unit: 'count' },
   { value: 'iterations', label: 'Iterations', unit: 'count' },
-  { value: 'vus', label: 'Virtual Users', unit: 'count' },
   { value: 'data_received', label: 'Data Received', unit: 'bytes' },
   { value: 'data_sent', label: 'Data Sent', unit: 'bytes' },
 ];
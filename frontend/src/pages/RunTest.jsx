@@ -1,9 +1,23 @@
 import { useEffect, useState } from "react";
 import { useLocation } from "react-router-dom";
-import { runTest } from "../api/testApi";
+import { runTest, getTestResult } from "../api/testApi";
 import { getScripts } from "../api/scriptApi";
 import { Play, Settings, CheckCircle, AlertCircle } from "lucide-react";
 import ResultCharts from "../components/ResultChart";
+import LiveMetrics from "../components/LiveMetrics";
+
+const POLL_INTERVAL_MS = 1000;
+
+// Polls the background run until it is finished or aborted.
+async function waitForResult(testId) {
+  for (;;) {
+    const run = await getTestResult(testId);
+    if (run.status === 'finished' || run.status === 'aborted') {
+      return run.result;
+    }
+    await new Promise((resolve) => setTimeout(resolve, POLL_INTERVAL_MS));
+  }
+}
 
 export default function RunTest() {
   const location = useLocation();
@@ -26,6 +40,7 @@ export default function RunTest() {
   const [loading, setLoading] = useState(false);
   const [progress, setProgress] = useState(0);
   const [error, setError] = useState(null);
+  const [testId, setTestId] = useState(null);
 
   useEffect(() => {
     loadScripts();
@@ -72,7 +87,9 @@ export default function RunTest() {
       };
 
       console.log('Running test with config:', config);
-      const testResult = await runTest(config); // Returns result directly
+      const run = await runTest(config); // Returns the queued run
+      setTestId(run.testId);
+      const testResult = await waitForResult(run.testId);
       console.log('Test result:', testResult);
       setResult(testResult);
       setProgress(100);
@@ -227,6 +244,8 @@ export default function RunTest() {
         </div>
       )}
 
+      {loading && testId && <LiveMetrics testId={testId} isRunning={loading} />}
+
       {/* Results */}
       {result && (
         <div className="card">
@@ -81,11 +81,6 @@ function buildOptions(config) {
   // Thresholds
   if (Object.keys(config.thresholds).length > 0) {
     options.thresholds = config.thresholds;
-  } else {
-    options.thresholds = {
-      'http_req_duration': ['p(95)<500'],
-      'http_req_failed': ['rate<0.1']
-    };
   }
 
   // Tags